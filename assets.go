@@ -0,0 +1,151 @@
+package got
+
+import (
+	"errors"
+	"html/template"
+	"slices"
+	"strings"
+)
+
+// assetsFuncs are the functions collecting the scripts and styles of a
+// render. Pages calling them are rendered into a buffer, so that the layout
+// may output the collected tags before the partials pushing them ran:
+//
+//	<head>{{styles}}</head>
+//	<body>{{template "content" .}}{{scripts}}</body>
+//
+//	{{define "content"}}{{push_style "/map.css"}}{{push_script "/map.js" (dict "defer" true)}}{{end}}
+var assetsFuncs = []string{"push_script", "push_style", "scripts", "styles"}
+
+var errAssetsNotBound = errors.New("assets: function is not bound to a render")
+
+// pushFn and flushFn are the types of the asset functions, unbound ones
+// return errAssetsNotBound.
+type (
+	pushFn  func(url string, attrs ...any) (string, error)
+	flushFn func() (template.HTML, error)
+)
+
+// defaultAssetsFuncs returns the unbound asset functions missing from
+// funcMap.
+func defaultAssetsFuncs(funcMap template.FuncMap) template.FuncMap {
+	unbound := template.FuncMap{
+		"push_script": pushFn(func(string, ...any) (string, error) { return "", errAssetsNotBound }),
+		"push_style":  pushFn(func(string, ...any) (string, error) { return "", errAssetsNotBound }),
+		"scripts":     flushFn(func() (template.HTML, error) { return "", errAssetsNotBound }),
+		"styles":      flushFn(func() (template.HTML, error) { return "", errAssetsNotBound }),
+	}
+
+	for name := range funcMap {
+		delete(unbound, name)
+	}
+	return unbound
+}
+
+// boundAssetsFuncs returns the names of the asset functions of funcMap,
+// leaving out the ones replaced by other functions.
+func boundAssetsFuncs(funcMap template.FuncMap) []string {
+	var names []string
+	for _, name := range assetsFuncs {
+		switch funcMap[name].(type) {
+		case pushFn, flushFn:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type asset struct {
+	url   string
+	attrs any
+}
+
+// assets collects the deduplicated scripts and styles pushed during a render.
+type assets struct {
+	scripts []asset
+	styles  []asset
+}
+
+func (a *assets) reset() {
+	a.scripts = a.scripts[:0]
+	a.styles = a.styles[:0]
+}
+
+func (a *assets) push(list []asset, url string, attrs []any) []asset {
+	if slices.ContainsFunc(list, func(item asset) bool { return item.url == url }) {
+		return list
+	}
+
+	item := asset{url: url}
+	if len(attrs) > 0 {
+		item.attrs = attrs[0]
+	}
+	return append(list, item)
+}
+
+func (a *assets) value(key string) string {
+	switch key {
+	case "scripts":
+		return a.render(a.scripts, "<script", "src", "></script>", nil)
+	case "styles":
+		return a.render(a.styles, "<link", "href", ">", map[string]any{"rel": "stylesheet"})
+	}
+	return ""
+}
+
+func (a *assets) render(list []asset, open, attr, end string, defaults map[string]any) string {
+	var b strings.Builder
+	for _, item := range list {
+		attrs := toStringMap(item.attrs)
+		if attrs == nil {
+			attrs = make(map[string]any, len(defaults)+1)
+		}
+		for key, value := range defaults {
+			if _, ok := attrs[key]; !ok {
+				attrs[key] = value
+			}
+		}
+		attrs[attr] = item.url
+
+		b.WriteString(open)
+		b.WriteString(string(Attrs(attrs)))
+		b.WriteString(end)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// funcs returns the asset functions of funcMap bound to a. When deferred,
+// scripts and styles output markers filled once the render completes,
+// otherwise they output what was pushed so far.
+func (a *assets) funcs(funcMap template.FuncMap, deferred bool) template.FuncMap {
+	flush := func(key string) flushFn {
+		return func() (template.HTML, error) {
+			if deferred {
+				return template.HTML(marker(key)), nil
+			}
+			return template.HTML(a.value(key)), nil
+		}
+	}
+
+	bound := template.FuncMap{
+		"push_script": pushFn(func(url string, attrs ...any) (string, error) {
+			a.scripts = a.push(a.scripts, url, attrs)
+			return "", nil
+		}),
+		"push_style": pushFn(func(url string, attrs ...any) (string, error) {
+			a.styles = a.push(a.styles, url, attrs)
+			return "", nil
+		}),
+		"scripts": flush("scripts"),
+		"styles":  flush("styles"),
+	}
+
+	names := boundAssetsFuncs(funcMap)
+	for name := range bound {
+		if !slices.Contains(names, name) {
+			delete(bound, name)
+		}
+	}
+	return bound
+}
@@ -0,0 +1,111 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssets_Value(t *testing.T) {
+	var a assets
+	a.scripts = a.push(a.scripts, "/app.js", nil)
+	a.scripts = a.push(a.scripts, "/map.js", []any{map[string]any{"defer": true, "type": "module"}})
+	a.scripts = a.push(a.scripts, "/app.js", []any{map[string]any{"async": true}})
+	a.styles = a.push(a.styles, "/app.css", []any{map[string]any{"media": "print"}})
+
+	assert.Equal(t, "<script src=\"/app.js\"></script>\n<script defer src=\"/map.js\" type=\"module\"></script>\n", a.value("scripts"))
+	assert.Equal(t, "<link href=\"/app.css\" media=\"print\" rel=\"stylesheet\">\n", a.value("styles"))
+	assert.Empty(t, a.value("unknown"))
+
+	a.reset()
+	assert.Empty(t, a.value("scripts"))
+	assert.Empty(t, a.value("styles"))
+}
+
+func TestAssets_Funcs(t *testing.T) {
+	funcs := defaultAssetsFuncs(template.FuncMap{"styles": func() string { return "custom" }})
+	assert.NotContains(t, funcs, "styles")
+	assert.Contains(t, funcs, "scripts")
+
+	_, err := funcs["push_script"].(pushFn)("/app.js")
+	assert.ErrorIs(t, err, errAssetsNotBound)
+
+	assert.Equal(t, []string{"push_script", "push_style", "scripts"}, boundAssetsFuncs(funcs))
+
+	var a assets
+	bound := a.funcs(funcs, false)
+	assert.NotContains(t, bound, "styles")
+
+	_, err = bound["push_script"].(pushFn)("/app.js")
+	require.NoError(t, err)
+
+	out, err := bound["scripts"].(flushFn)()
+	require.NoError(t, err)
+	assert.Equal(t, template.HTML("<script src=\"/app.js\"></script>\n"), out)
+
+	out, err = a.funcs(funcs, true)["scripts"].(flushFn)()
+	require.NoError(t, err)
+	assert.Equal(t, template.HTML(marker("scripts")), out)
+}
+
+func TestTheme_Assets(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", `<head>{{styles}}</head><body>{{template "content" .}}{{scripts}}</body>`)
+	store.Add("test", "partials/map", `{{define "map"}}{{push_style "/map.css"}}{{push_script "/map.js" (dict "defer" true)}}<div id="map"></div>{{end}}`)
+	store.Add("test", "page", `<!-- layouts/base -->{{define "content"}}{{template "map"}}{{template "map"}}{{push_script "/page.js"}}{{end}}`)
+
+	theme := NewTheme("test", store)
+	theme.SetFuncMap(Funcs)
+	theme.SetAutoInclude("partials/*")
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.Write(context.Background(), &buf, "page", nil))
+	assert.Equal(t,
+		"<head><link href=\"/map.css\" rel=\"stylesheet\">\n</head>"+
+			"<body><div id=\"map\"></div><div id=\"map\"></div>"+
+			"<script defer src=\"/map.js\"></script>\n<script src=\"/page.js\"></script>\n</body>",
+		buf.String(),
+	)
+
+	c, err := theme.compile(context.Background(), "page")
+	require.NoError(t, err)
+	assert.True(t, c.buffered)
+	assert.NotNil(t, c.instances)
+
+	t.Run("renders are isolated", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				var buf bytes.Buffer
+				assert.NoError(t, theme.Write(context.Background(), &buf, "page", nil))
+				assert.Equal(t, 1, strings.Count(buf.String(), "/page.js"))
+			})
+		}
+		wg.Wait()
+	})
+
+	t.Run("unbuffered without assets", func(t *testing.T) {
+		store.Add("test", "plain", `<p>plain</p>`)
+
+		// auto-included partials pushing assets would buffer every page
+		c, err := NewTheme("test", store).compile(context.Background(), "plain")
+		require.NoError(t, err)
+		assert.False(t, c.buffered)
+		assert.Nil(t, c.instances)
+	})
+
+	t.Run("compiled", func(t *testing.T) {
+		tpl, err := theme.Compiled(context.Background(), "page")
+		require.NoError(t, err)
+
+		var buf strings.Builder
+		require.NoError(t, tpl.ExecuteTemplate(&buf, "content", nil))
+		assert.Equal(t, `<div id="map"></div><div id="map"></div>`, buf.String())
+	})
+}
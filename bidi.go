@@ -0,0 +1,110 @@
+package got
+
+import (
+	"html/template"
+
+	"golang.org/x/text/language"
+)
+
+// rtlScripts are the scripts written from right to left.
+var rtlScripts = map[string]struct{}{
+	"Adlm": {}, "Arab": {}, "Hebr": {}, "Mand": {}, "Mend": {}, "Nkoo": {},
+	"Rohg": {}, "Samr": {}, "Syrc": {}, "Thaa": {}, "Yezi": {},
+}
+
+// LocaleInfo describes the writing direction of a locale, for templates to
+// lay out pages without testing languages:
+//
+//	<html lang="{{(locale).Lang}}" dir="{{(locale).Dir}}">
+type LocaleInfo struct {
+	Tag language.Tag
+	// Lang is the BCP 47 tag, for the lang attribute.
+	Lang string
+	// Dir is "rtl" or "ltr", for the dir attribute.
+	Dir string
+	RTL bool
+	// Start and End are the physical sides of the logical start and end of
+	// a line, "left" and "right" in left-to-right locales.
+	Start string
+	End   string
+}
+
+// Locale returns the direction metadata of the locale.
+func Locale(locale language.Tag) LocaleInfo {
+	info := LocaleInfo{Tag: locale, Lang: locale.String(), Dir: "ltr", Start: "left", End: "right"}
+	if IsRTL(locale) {
+		info.Dir, info.RTL, info.Start, info.End = "rtl", true, "right", "left"
+	}
+	return info
+}
+
+// IsRTL reports whether the locale is written from right to left.
+func IsRTL(locale language.Tag) bool {
+	script, confidence := locale.Script()
+	if confidence == language.No {
+		return false
+	}
+	_, ok := rtlScripts[script.String()]
+	return ok
+}
+
+// BidiIsolate wraps the text in Unicode first strong isolate marks, so text
+// of unknown direction, such as a user name, doesn't reorder its
+// surroundings. It suits contexts where markup isn't allowed, use a <bdi>
+// element in HTML text.
+func BidiIsolate(text string) string {
+	return "\u2068" + text + "\u2069"
+}
+
+// Bdi returns the escaped text isolated in a <bdi> element.
+func Bdi(text string) template.HTML {
+	return template.HTML("<bdi>" + template.HTMLEscapeString(text) + "</bdi>")
+}
+
+var englishBidiFuncs = bidiFuncs(language.English)
+
+// bidiFuncs returns the direction functions of the render locale, see
+// Funcs and LocaleFuncs.
+func bidiFuncs(locale language.Tag) template.FuncMap {
+	info := Locale(locale)
+
+	// of the render locale, or of the locale given as argument
+	infoOf := func(tag []string) LocaleInfo {
+		if len(tag) > 0 {
+			if parsed, err := language.Parse(tag[0]); err == nil {
+				return Locale(parsed)
+			}
+		}
+		return info
+	}
+
+	return template.FuncMap{
+		"locale": func(tag ...string) LocaleInfo {
+			return infoOf(tag)
+		},
+		"dir": func(tag ...string) string {
+			return infoOf(tag).Dir
+		},
+		"is_rtl": func(tag ...string) bool {
+			return infoOf(tag).RTL
+		},
+		"start_side": func() string {
+			return info.Start
+		},
+		"end_side": func() string {
+			return info.End
+		},
+		// dir_choose returns ltr or rtl depending on the direction, e.g. to
+		// toggle classes without logical properties:
+		//
+		//	<i class="{{dir_choose "ml-2" "mr-2"}}"></i>
+		"dir_choose": func(ltr, rtl any) any {
+			if info.RTL {
+				return rtl
+			}
+			return ltr
+		},
+		"bdi":          Bdi,
+		"bidi_isolate": BidiIsolate,
+	}
+}
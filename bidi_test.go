@@ -0,0 +1,75 @@
+package got
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want LocaleInfo
+	}{
+		{tag: "en-US", want: LocaleInfo{Lang: "en-US", Dir: "ltr", Start: "left", End: "right"}},
+		{tag: "ar", want: LocaleInfo{Lang: "ar", Dir: "rtl", RTL: true, Start: "right", End: "left"}},
+		{tag: "he-IL", want: LocaleInfo{Lang: "he-IL", Dir: "rtl", RTL: true, Start: "right", End: "left"}},
+		{tag: "fa", want: LocaleInfo{Lang: "fa", Dir: "rtl", RTL: true, Start: "right", End: "left"}},
+		{tag: "az-Arab", want: LocaleInfo{Lang: "az-Arab", Dir: "rtl", RTL: true, Start: "right", End: "left"}},
+		{tag: "az-Latn", want: LocaleInfo{Lang: "az-Latn", Dir: "ltr", Start: "left", End: "right"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			tag := language.MustParse(tt.tag)
+			tt.want.Tag = tag
+			assert.Equal(t, tt.want, Locale(tag))
+			assert.Equal(t, tt.want.RTL, IsRTL(tag))
+		})
+	}
+}
+
+func TestBidiIsolate(t *testing.T) {
+	assert.Equal(t, "\u2068שלום\u2069", BidiIsolate("שלום"))
+	assert.Equal(t, `<bdi>&lt;b&gt;</bdi>`, string(Bdi("<b>")))
+}
+
+func TestTheme_Write_Direction(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<html lang="{{(locale).Lang}}" dir="{{dir}}">`+
+		`<p class="{{dir_choose "ml-2" "mr-2"}}" style="float: {{start_side}}">{{bdi .}}</p>`+
+		`{{if is_rtl "ar"}}ar{{end}}{{if not (is_rtl "fr")}}fr{{end}}`+
+		`<img alt="{{bidi_isolate .}}"></html>`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "default",
+			ctx:  context.Background(),
+			want: `<html lang="en" dir="ltr"><p class="ml-2" style="float: left"><bdi>Dana</bdi></p>arfr<img alt="` + "\u2068Dana\u2069" + `"></html>`,
+		},
+		{
+			name: "rtl locale",
+			ctx:  WithLocale(context.Background(), language.Hebrew),
+			want: `<html lang="he" dir="rtl"><p class="mr-2" style="float: right"><bdi>Dana</bdi></p>arfr<img alt="` + "\u2068Dana\u2069" + `"></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			require.NoError(t, theme.Write(tt.ctx, &out, "page", "Dana"))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package got
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	_ Store  = (*Bundle)(nil)
+	_ Lister = (*Bundle)(nil)
+)
+
+// BundleFormat is the version of the bundle format written by ExportBundle.
+const BundleFormat = 1
+
+// Limits of the decompressed content of imported bundles, so a small
+// compressed bundle can't exhaust the memory.
+const (
+	BundleMaxFileSize = 16 << 20
+	BundleMaxSize     = 128 << 20
+)
+
+const (
+	bundleManifest  = "manifest.json"
+	bundleSignature = "manifest.sig"
+	bundleTemplates = "templates/"
+	bundleAssets    = "assets/"
+)
+
+var (
+	// ErrInvalidBundle is returned when reading a malformed or corrupted
+	// bundle.
+	ErrInvalidBundle = errors.New("invalid bundle")
+
+	// ErrUntrustedBundle is returned when a bundle isn't signed by a
+	// trusted key.
+	ErrUntrustedBundle = errors.New("untrusted bundle")
+)
+
+// BundleManifest describes the content of a theme bundle.
+type BundleManifest struct {
+	Format    int               `json:"format"`
+	Name      string            `json:"name"`
+	Version   string            `json:"version,omitempty"`
+	Parent    string            `json:"parent,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Templates []BundleTemplate  `json:"templates"`
+	Assets    []BundleFile      `json:"assets,omitempty"`
+}
+
+// BundleTemplate describes a template of a bundle. The layout directive is
+// kept apart from the content, so templates are restored regardless of the
+// directive syntax.
+type BundleTemplate struct {
+	BundleFile
+	Path string            `json:"path"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// BundleFile is a file of a bundle and the hex SHA-256 of its content.
+type BundleFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleOptions are the optional parts of an exported bundle.
+type BundleOptions struct {
+	Version string
+	Meta    map[string]string
+	// Assets are static files shipped with the theme, such as stylesheets
+	// and images.
+	Assets fs.FS
+	// SigningKey signs the bundle when set, see ImportSignedBundle.
+	SigningKey ed25519.PrivateKey
+}
+
+// ExportBundle writes the templates of the theme, without the ones of its
+// parents, to w as a single file bundle, see ImportBundle. The store must
+// implement Lister.
+//
+// A bundle is a gzipped tar archive holding manifest.json, the templates
+// under templates/ and the assets under assets/. The manifest lists the
+// SHA-256 of every file, a signed bundle holds the ed25519 signature of the
+// manifest in manifest.sig.
+func (t *Theme) ExportBundle(ctx context.Context, w io.Writer, options BundleOptions) error {
+	lister, ok := t.store.(Lister)
+	if !ok {
+		return fmt.Errorf("theme: failed to export %s: %w", t.name, ErrNotLister)
+	}
+	names, err := lister.List(ctx, t.name)
+	if err != nil {
+		return fmt.Errorf("theme: failed to list templates of %s: %w", t.name, err)
+	}
+
+	manifest := BundleManifest{
+		Format:  BundleFormat,
+		Name:    t.name,
+		Version: options.Version,
+		Meta:    options.Meta,
+	}
+	if parent := t.Parent(); parent != nil {
+		manifest.Parent = parent.name
+	}
+
+	files := make(map[string][]byte)
+
+	for _, name := range names {
+		item, err := t.store.Find(ctx, t.name, name)
+		if err != nil {
+			return fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
+		}
+
+		content := []byte(item.Content())
+		entry := BundleTemplate{BundleFile: newBundleFile(name, content), Path: item.Path()}
+		if m, ok := item.(Metadata); ok {
+			entry.Meta = m.Meta()
+		}
+
+		manifest.Templates = append(manifest.Templates, entry)
+		files[bundleTemplates+name] = content
+	}
+
+	if options.Assets != nil {
+		err = fs.WalkDir(options.Assets, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			content, err := fs.ReadFile(options.Assets, name)
+			if err != nil {
+				return err
+			}
+
+			manifest.Assets = append(manifest.Assets, newBundleFile(name, content))
+			files[bundleAssets+name] = content
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("theme: failed to read assets of %s: %w", t.name, err)
+		}
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("theme: failed to export %s: %w", t.name, err)
+	}
+
+	if options.SigningKey != nil {
+		if len(options.SigningKey) != ed25519.PrivateKeySize {
+			return fmt.Errorf("theme: failed to sign %s: invalid ed25519 private key", t.name)
+		}
+		files[bundleSignature] = ed25519.Sign(options.SigningKey, raw)
+	}
+
+	if err = writeBundle(w, raw, files); err != nil {
+		return fmt.Errorf("theme: failed to export %s: %w", t.name, err)
+	}
+	return nil
+}
+
+func newBundleFile(name string, content []byte) BundleFile {
+	sum := sha256.Sum256(content)
+	return BundleFile{Name: name, SHA256: hex.EncodeToString(sum[:])}
+}
+
+func writeBundle(w io.Writer, manifest []byte, files map[string][]byte) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	write := func(name string, content []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Format:   tar.FormatPAX,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+
+	if err := write(bundleManifest, manifest); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		if err := write(name, files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Bundle is a theme read from a bundle. It is a store serving the templates
+// of the theme named by its manifest.
+type Bundle struct {
+	manifest  BundleManifest
+	templates map[string]*tmpl
+	assets    map[string][]byte
+	signer    ed25519.PublicKey
+}
+
+// ImportBundle reads a bundle written by ExportBundle, verifying the
+// checksums of its files. The signature, if any, isn't verified.
+func ImportBundle(r io.Reader) (*Bundle, error) {
+	files, err := readBundle(r, BundleMaxFileSize, BundleMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: %w: %w", ErrInvalidBundle, err)
+	}
+	return newBundle(files)
+}
+
+// ImportSignedBundle reads a bundle like ImportBundle, only if it is signed
+// by one of the trusted public keys.
+func ImportSignedBundle(r io.Reader, trusted ...ed25519.PublicKey) (*Bundle, error) {
+	files, err := readBundle(r, BundleMaxFileSize, BundleMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("bundle: %w: %w", ErrInvalidBundle, err)
+	}
+
+	signature, ok := files[bundleSignature]
+	if !ok {
+		return nil, fmt.Errorf("bundle: %w: not signed", ErrUntrustedBundle)
+	}
+
+	i := slices.IndexFunc(trusted, func(key ed25519.PublicKey) bool {
+		return len(key) == ed25519.PublicKeySize && ed25519.Verify(key, files[bundleManifest], signature)
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("bundle: %w: no trusted key matches the signature", ErrUntrustedBundle)
+	}
+
+	b, err := newBundle(files)
+	if err != nil {
+		return nil, err
+	}
+	b.signer = trusted[i]
+	return b, nil
+}
+
+func newBundle(files map[string][]byte) (*Bundle, error) {
+	raw, ok := files[bundleManifest]
+	if !ok {
+		return nil, fmt.Errorf("bundle: %w: missing %s", ErrInvalidBundle, bundleManifest)
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("bundle: %w: %w", ErrInvalidBundle, err)
+	}
+	if manifest.Format != BundleFormat {
+		return nil, fmt.Errorf("bundle: %w: unsupported format %d", ErrInvalidBundle, manifest.Format)
+	}
+
+	b := &Bundle{
+		manifest:  manifest,
+		templates: make(map[string]*tmpl, len(manifest.Templates)),
+		assets:    make(map[string][]byte, len(manifest.Assets)),
+	}
+
+	for _, entry := range manifest.Templates {
+		content, err := bundleContent(files, bundleTemplates, entry.BundleFile)
+		if err != nil {
+			return nil, err
+		}
+
+		b.templates[entry.Name] = &tmpl{
+			theme:   manifest.Name,
+			name:    entry.Name,
+			path:    entry.Path,
+			content: string(content),
+			meta:    entry.Meta,
+			info:    TemplateInfo{Hash: entry.SHA256, Size: int64(len(content))},
+		}
+	}
+
+	for _, entry := range manifest.Assets {
+		content, err := bundleContent(files, bundleAssets, entry)
+		if err != nil {
+			return nil, err
+		}
+		b.assets[entry.Name] = content
+	}
+
+	return b, nil
+}
+
+// readBundle reads the regular files of a bundle, failing when a file is
+// larger than maxFile or the files are larger than maxSize in total.
+func readBundle(r io.Reader, maxFile, maxSize int64) (map[string][]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	files := make(map[string][]byte)
+
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("duplicate file %s", name)
+		}
+
+		limit := min(maxFile, maxSize)
+		if hdr.Size > limit {
+			return nil, fmt.Errorf("file %s exceeds the size limit", name)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(content)) > limit {
+			return nil, fmt.Errorf("file %s exceeds the size limit", name)
+		}
+		files[name] = content
+		maxSize -= int64(len(content))
+	}
+}
+
+func bundleContent(files map[string][]byte, dir string, entry BundleFile) ([]byte, error) {
+	content, ok := files[dir+entry.Name]
+	if !ok {
+		return nil, fmt.Errorf("bundle: %w: missing %s%s", ErrInvalidBundle, dir, entry.Name)
+	}
+	if newBundleFile(entry.Name, content).SHA256 != strings.ToLower(entry.SHA256) {
+		return nil, fmt.Errorf("bundle: %w: checksum mismatch of %s%s", ErrInvalidBundle, dir, entry.Name)
+	}
+	return content, nil
+}
+
+// Manifest returns the manifest of the bundle.
+func (b *Bundle) Manifest() BundleManifest {
+	return b.manifest
+}
+
+// Signer returns the trusted key the bundle was verified with by
+// ImportSignedBundle, nil for bundles read by ImportBundle.
+func (b *Bundle) Signer() ed25519.PublicKey {
+	return b.signer
+}
+
+// Name returns the name of the bundled theme.
+func (b *Bundle) Name() string {
+	return b.manifest.Name
+}
+
+// Asset returns the content of the named asset.
+func (b *Bundle) Asset(name string) ([]byte, bool) {
+	content, ok := b.assets[name]
+	return content, ok
+}
+
+func (b *Bundle) Find(_ context.Context, theme, name string) (Template, error) {
+	if theme == b.manifest.Name {
+		if item, ok := b.templates[name]; ok {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("bundle: template %s/%s not found: %w", theme, name, ErrTemplateNotFound)
+}
+
+func (b *Bundle) List(_ context.Context, theme string) ([]string, error) {
+	if theme != b.manifest.Name {
+		return nil, nil
+	}
+	return slices.Sorted(maps.Keys(b.templates)), nil
+}
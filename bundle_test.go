@@ -0,0 +1,254 @@
+package got
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestBundle(t *testing.T, key ed25519.PrivateKey) []byte {
+	t.Helper()
+
+	store := NewStoreMemory()
+	store.Add("shop", "layouts/base.html", `<html>{{block "content" .}}{{end}}</html>`)
+	store.Add("shop", "index.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{.}}{{end}}`)
+	store.SetDirectiveParser(FrontMatterDirective)
+	store.Add("shop", "about.html", "---\nlayout: layouts/base.html\ntitle: About\n---\n"+`{{define "content"}}about{{end}}`)
+
+	theme := NewTheme("shop", store)
+	theme.SetParent(NewTheme("base", store))
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.ExportBundle(context.Background(), &buf, BundleOptions{
+		Version:    "1.2.0",
+		Meta:       map[string]string{"author": "acme"},
+		Assets:     fstest.MapFS{"css/app.css": {Data: []byte("body{}")}},
+		SigningKey: key,
+	}))
+	return buf.Bytes()
+}
+
+func TestBundle_ExportImport(t *testing.T) {
+	b, err := ImportBundle(bytes.NewReader(exportTestBundle(t, nil)))
+	require.NoError(t, err)
+
+	manifest := b.Manifest()
+	assert.Equal(t, BundleFormat, manifest.Format)
+	assert.Equal(t, "shop", b.Name())
+	assert.Equal(t, "1.2.0", manifest.Version)
+	assert.Equal(t, "base", manifest.Parent)
+	assert.Equal(t, map[string]string{"author": "acme"}, manifest.Meta)
+	require.Len(t, manifest.Templates, 3)
+	require.Len(t, manifest.Assets, 1)
+
+	css, ok := b.Asset("css/app.css")
+	assert.True(t, ok)
+	assert.Equal(t, "body{}", string(css))
+
+	names, err := b.List(context.Background(), "shop")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"about.html", "index.html", "layouts/base.html"}, names)
+
+	names, err = b.List(context.Background(), "other")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	about, err := b.Find(context.Background(), "shop", "about.html")
+	require.NoError(t, err)
+	assert.Equal(t, "layouts/base.html", about.Path())
+	assert.Equal(t, "About", about.(Metadata).Meta()["title"])
+
+	_, err = b.Find(context.Background(), "other", "about.html")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	theme := NewTheme("shop", b)
+	for page, want := range map[string]string{
+		"index.html": "<html>hi</html>",
+		"about.html": "<html>about</html>",
+	} {
+		var out strings.Builder
+		require.NoError(t, theme.Write(context.Background(), &out, page, "hi"))
+		assert.Equal(t, want, strings.TrimSpace(out.String()))
+	}
+}
+
+func writeTestArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestImportBundle_Invalid(t *testing.T) {
+	sum := newBundleFile("a.html", []byte("a")).SHA256
+
+	tests := []struct {
+		name    string
+		archive []byte
+	}{
+		{
+			name:    "not gzip",
+			archive: []byte("plain"),
+		},
+		{
+			name:    "missing manifest",
+			archive: writeTestArchive(t, map[string]string{"templates/a.html": "a"}),
+		},
+		{
+			name:    "unsupported format",
+			archive: writeTestArchive(t, map[string]string{"manifest.json": `{"format":2,"name":"x"}`}),
+		},
+		{
+			name: "missing template",
+			archive: writeTestArchive(t, map[string]string{
+				"manifest.json": `{"format":1,"name":"x","templates":[{"name":"a.html","sha256":"` + sum + `"}]}`,
+			}),
+		},
+		{
+			name: "checksum mismatch",
+			archive: writeTestArchive(t, map[string]string{
+				"manifest.json":    `{"format":1,"name":"x","templates":[{"name":"a.html","sha256":"` + sum + `"}]}`,
+				"templates/a.html": "tampered",
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportBundle(bytes.NewReader(tt.archive))
+			assert.ErrorIs(t, err, ErrInvalidBundle)
+		})
+	}
+}
+
+func TestImportBundle_SizeLimit(t *testing.T) {
+	// a gzip bomb, a few kilobytes expanding past the file size limit
+	archive := writeTestArchive(t, map[string]string{"templates/a.html": strings.Repeat("a", BundleMaxFileSize+1)})
+	require.Less(t, len(archive), 1<<20)
+
+	_, err := ImportBundle(bytes.NewReader(archive))
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+	assert.ErrorContains(t, err, "file templates/a.html exceeds the size limit")
+}
+
+func TestReadBundle_Limits(t *testing.T) {
+	archive := writeTestArchive(t, map[string]string{"a": "aaaa", "b": "bbbb", "c": "cccc"})
+
+	tests := []struct {
+		name    string
+		maxFile int64
+		maxSize int64
+		wantErr bool
+	}{
+		{"within the limits", 4, 12, false},
+		{"file too large", 3, 12, true},
+		{"bundle too large", 4, 11, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := readBundle(bytes.NewReader(archive), tt.maxFile, tt.maxSize)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "exceeds the size limit")
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, files, 3)
+		})
+	}
+}
+
+func readTestArchive(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(content)
+	}
+}
+
+func TestImportSignedBundle(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	other, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	signed := exportTestBundle(t, private)
+
+	b, err := ImportSignedBundle(bytes.NewReader(signed), other, public)
+	require.NoError(t, err)
+	assert.Equal(t, public, b.Signer())
+	assert.Equal(t, "shop", b.Name())
+
+	b, err = ImportBundle(bytes.NewReader(signed))
+	require.NoError(t, err)
+	assert.Nil(t, b.Signer())
+
+	files := readTestArchive(t, signed)
+	files["manifest.json"] = strings.Replace(files["manifest.json"], `"1.2.0"`, `"9.9.9"`, 1)
+	tampered := writeTestArchive(t, files)
+
+	tests := []struct {
+		name    string
+		archive []byte
+		keys    []ed25519.PublicKey
+		err     error
+	}{
+		{name: "unsigned", archive: exportTestBundle(t, nil), keys: []ed25519.PublicKey{public}, err: ErrUntrustedBundle},
+		{name: "untrusted key", archive: signed, keys: []ed25519.PublicKey{other}, err: ErrUntrustedBundle},
+		{name: "no trusted keys", archive: signed, err: ErrUntrustedBundle},
+		{name: "invalid key", archive: signed, keys: []ed25519.PublicKey{public[:8]}, err: ErrUntrustedBundle},
+		{name: "tampered manifest", archive: tampered, keys: []ed25519.PublicKey{public}, err: ErrUntrustedBundle},
+		{name: "not gzip", archive: []byte("plain"), keys: []ed25519.PublicKey{public}, err: ErrInvalidBundle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportSignedBundle(bytes.NewReader(tt.archive), tt.keys...)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestTheme_ExportBundle_InvalidKey(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("shop", "index.html", "index")
+
+	err := NewTheme("shop", store).ExportBundle(context.Background(), io.Discard, BundleOptions{SigningKey: make(ed25519.PrivateKey, 8)})
+	assert.EqualError(t, err, "theme: failed to sign shop: invalid ed25519 private key")
+}
+
+func TestTheme_ExportBundle_NotLister(t *testing.T) {
+	err := NewTheme("shop", &MockStore{}).ExportBundle(context.Background(), io.Discard, BundleOptions{})
+	assert.ErrorIs(t, err, ErrNotLister)
+	assert.ErrorContains(t, err, "theme: failed to export shop")
+}
@@ -0,0 +1,438 @@
+package got
+
+import (
+	"container/list"
+	"errors"
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+const cacheShards = 32
+
+var errCacheBuildPanic = errors.New("cache: build panicked")
+
+// CacheBudget is a memory budget shared by the caches of several themes, so
+// the templates of all the themes of an application fit in a single limit:
+//
+//	budget := got.NewCacheBudget(256 << 20)
+//	shop.UseCacheBudget(budget)
+//	blog.UseCacheBudget(budget)
+//
+// Once the budget is exceeded, the least recently used entries are evicted,
+// whichever theme they belong to.
+type CacheBudget struct {
+	mu    sync.Mutex
+	limit int64
+	size  int64
+	lru   list.List
+}
+
+// NewCacheBudget returns a budget of limit bytes, zero or a negative value
+// meaning unlimited.
+func NewCacheBudget(limit int64) *CacheBudget {
+	return &CacheBudget{limit: max(limit, 0)}
+}
+
+// Limit returns the size limit in bytes, zero means unlimited.
+func (b *CacheBudget) Limit() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.limit
+}
+
+// SetLimit sets the size limit in bytes and evicts the entries exceeding
+// it. Zero or a negative value disables the limit.
+func (b *CacheBudget) SetLimit(limit int64) {
+	b.mu.Lock()
+	b.limit = max(limit, 0)
+	b.mu.Unlock()
+
+	b.evict()
+}
+
+// Size returns the approximate size in bytes of the entries held by the
+// caches using the budget.
+func (b *CacheBudget) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+// budgetEntry is the part of a cache entry tracked by its budget.
+//
+// The budget keeps its entries in a list from the most to the least recently
+// added. Reading an entry only marks it as used, instead of moving it under
+// the lock of the budget; evict moves used entries back to the front as it
+// meets them at the back, so the list approximates the recency order in
+// constant time per operation.
+type budgetEntry struct {
+	key  string
+	gen  budgetGen
+	size int64
+	used atomic.Bool
+	elem *list.Element
+}
+
+// budgetGen is a cache generation whose entries are tracked by a budget.
+type budgetGen interface {
+	remove(e *budgetEntry)
+}
+
+func (e *budgetEntry) touch() {
+	if !e.used.Load() {
+		e.used.Store(true)
+	}
+}
+
+func (b *CacheBudget) add(e *budgetEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e.elem = b.lru.PushFront(e)
+	b.size += e.size
+}
+
+func (b *CacheBudget) remove(e *budgetEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.unlink(e)
+}
+
+func (b *CacheBudget) unlink(e *budgetEntry) {
+	if e.elem != nil {
+		b.lru.Remove(e.elem)
+		e.elem = nil
+		b.size -= e.size
+	}
+}
+
+// evict removes the least recently used entries until the budget is met.
+// The entries are removed from their caches once the budget is unlocked, as
+// caches add entries while holding the lock of a shard.
+func (b *CacheBudget) evict() {
+	var victims []*budgetEntry
+
+	b.mu.Lock()
+	for b.limit > 0 && b.size > b.limit {
+		e := b.lru.Back().Value.(*budgetEntry)
+		if e.used.Load() {
+			e.used.Store(false)
+			b.lru.MoveToFront(e.elem)
+			continue
+		}
+		b.unlink(e)
+		victims = append(victims, e)
+	}
+	b.mu.Unlock()
+
+	for _, e := range victims {
+		e.gen.remove(e)
+	}
+}
+
+// cache is a sharded, generational key-value cache.
+//
+// Keys are spread over a fixed number of independently locked shards, so
+// concurrent renders of different templates don't contend on a single lock.
+// Clear swaps in a new, empty generation: readers that already loaded a
+// value keep using it, while the results of builds that started before
+// Clear are discarded.
+//
+// Concurrent LoadOrBuild calls for the same key within a generation share a
+// single build, which prevents a thundering rebuild after Clear.
+//
+// Every value has an approximate size in bytes, accounted in the budget of
+// the cache, which may be shared with other caches. The least recently used
+// values are evicted once the budget is exceeded.
+type cache[V any] struct {
+	seed   maphash.Seed
+	size   func(V) int64
+	budget atomic.Pointer[CacheBudget]
+	gen    atomic.Pointer[cacheGen[V]]
+
+	// removed, if set, is called with the values leaving the cache: evicted,
+	// deleted, replaced or cleared.
+	removed func(key string, value V)
+}
+
+type cacheGen[V any] struct {
+	cache     *cache[V]
+	budget    *CacheBudget
+	size      atomic.Int64
+	discarded atomic.Bool
+	shards    [cacheShards]cacheShard[V]
+}
+
+type cacheShard[V any] struct {
+	mu      sync.RWMutex
+	entries map[string]*cacheEntry[V]
+	calls   map[string]*cacheCall[V]
+}
+
+type cacheEntry[V any] struct {
+	budgetEntry
+	value V
+}
+
+type cacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// newCache returns a cache accounting its values in the budget, a budget of
+// its own without limit if nil.
+func newCache[V any](budget *CacheBudget, size func(V) int64) *cache[V] {
+	if budget == nil {
+		budget = NewCacheBudget(0)
+	}
+	c := &cache[V]{seed: maphash.MakeSeed(), size: size}
+	c.budget.Store(budget)
+	c.gen.Store(c.newGen())
+	return c
+}
+
+func (c *cache[V]) newGen() *cacheGen[V] {
+	g := &cacheGen[V]{cache: c, budget: c.budget.Load()}
+	for i := range g.shards {
+		g.shards[i].entries = make(map[string]*cacheEntry[V])
+		g.shards[i].calls = make(map[string]*cacheCall[V])
+	}
+	return g
+}
+
+func (g *cacheGen[V]) shard(key string) *cacheShard[V] {
+	return &g.shards[maphash.String(g.cache.seed, key)%cacheShards]
+}
+
+// remove removes the entry evicted by the budget, unless it was replaced.
+func (g *cacheGen[V]) remove(e *budgetEntry) {
+	s := g.shard(e.key)
+
+	s.mu.Lock()
+	old, ok := s.entries[e.key]
+	ok = ok && &old.budgetEntry == e
+	if ok {
+		delete(s.entries, e.key)
+		g.size.Add(-e.size)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		g.removed(old)
+	}
+}
+
+// removed reports the entry removed from the generation.
+func (g *cacheGen[V]) removed(e *cacheEntry[V]) {
+	if g.cache.removed != nil {
+		g.cache.removed(e.key, e.value)
+	}
+}
+
+// discard removes the entries of the generation from its budget. Entries
+// aren't added to a discarded generation.
+func (g *cacheGen[V]) discard() {
+	g.discarded.Store(true)
+
+	var removed []*cacheEntry[V]
+	for i := range g.shards {
+		s := &g.shards[i]
+		s.mu.Lock()
+		for _, e := range s.entries {
+			g.budget.remove(&e.budgetEntry)
+			if g.cache.removed != nil {
+				removed = append(removed, e)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	for _, e := range removed {
+		g.removed(e)
+	}
+}
+
+func (c *cache[V]) Load(key string) (V, bool) {
+	s := c.gen.Load().shard(key)
+
+	s.mu.RLock()
+	e, ok := s.entries[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	e.touch()
+	return e.value, true
+}
+
+func (c *cache[V]) Store(key string, value V) {
+	c.store(c.gen.Load(), key, value)
+}
+
+func (c *cache[V]) store(g *cacheGen[V], key string, value V) {
+	s := g.shard(key)
+
+	s.mu.Lock()
+	old := c.put(g, s, key, value)
+	s.mu.Unlock()
+
+	c.stored(g, old)
+}
+
+// put adds the value to the shard locked by the caller, returning the entry
+// it replaced, if any. The caller reports it with stored once unlocked.
+func (c *cache[V]) put(g *cacheGen[V], s *cacheShard[V], key string, value V) *cacheEntry[V] {
+	if g.discarded.Load() {
+		return nil
+	}
+
+	e := &cacheEntry[V]{budgetEntry: budgetEntry{key: key, gen: g}, value: value}
+	if c.size != nil {
+		e.size = c.size(value)
+	}
+
+	old := s.entries[key]
+	if old != nil {
+		g.size.Add(-old.size)
+		g.budget.remove(&old.budgetEntry)
+	}
+	s.entries[key] = e
+	g.size.Add(e.size)
+	g.budget.add(&e.budgetEntry)
+	return old
+}
+
+// stored reports the entry replaced by put and evicts the entries beyond
+// the budget.
+func (c *cache[V]) stored(g *cacheGen[V], old *cacheEntry[V]) {
+	if old != nil {
+		g.removed(old)
+	}
+	g.budget.evict()
+}
+
+func (c *cache[V]) Delete(key string) {
+	c.DeleteFunc(key, nil)
+}
+
+// DeleteFunc deletes the value of key if del, unless nil, returns true for
+// it.
+func (c *cache[V]) DeleteFunc(key string, del func(V) bool) {
+	g := c.gen.Load()
+	s := g.shard(key)
+
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	ok = ok && (del == nil || del(e.value))
+	if ok {
+		delete(s.entries, key)
+		g.size.Add(-e.size)
+		g.budget.remove(&e.budgetEntry)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		g.removed(e)
+	}
+}
+
+// LoadOrBuild returns the cached value for key or builds it. Failed builds
+// are not cached.
+func (c *cache[V]) LoadOrBuild(key string, build func() (V, error)) (V, error) {
+	if v, ok := c.Load(key); ok {
+		return v, nil
+	}
+
+	g := c.gen.Load()
+	s := g.shard(key)
+
+	s.mu.Lock()
+	if e, ok := s.entries[key]; ok {
+		s.mu.Unlock()
+		e.touch()
+		return e.value, nil
+	}
+	if call, ok := s.calls[key]; ok {
+		s.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &cacheCall[V]{done: make(chan struct{})}
+	s.calls[key] = call
+	s.mu.Unlock()
+
+	finished := false
+	defer func() {
+		if !finished {
+			call.err = errCacheBuildPanic
+		}
+
+		// the value is stored along with the removal of the call, so later
+		// callers either join the call or load the value
+		var old *cacheEntry[V]
+		s.mu.Lock()
+		delete(s.calls, key)
+		if call.err == nil {
+			old = c.put(g, s, key, call.value)
+		}
+		s.mu.Unlock()
+
+		if call.err == nil {
+			c.stored(g, old)
+		}
+		close(call.done)
+	}()
+
+	call.value, call.err = build()
+	finished = true
+	return call.value, call.err
+}
+
+func (c *cache[V]) Len() int {
+	g := c.gen.Load()
+
+	n := 0
+	for i := range g.shards {
+		s := &g.shards[i]
+		s.mu.RLock()
+		n += len(s.entries)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+// Size returns the approximate size in bytes of all cached values.
+func (c *cache[V]) Size() int64 {
+	return c.gen.Load().size.Load()
+}
+
+// Budget returns the size limit in bytes of the budget, zero means
+// unlimited.
+func (c *cache[V]) Budget() int64 {
+	return c.budget.Load().Limit()
+}
+
+// SetBudget sets the size limit in bytes of the budget and evicts values
+// exceeding it. Zero or a negative value disables the limit.
+func (c *cache[V]) SetBudget(budget int64) {
+	c.budget.Load().SetLimit(budget)
+}
+
+// UseBudget accounts the values in the budget, dropping the cached values.
+func (c *cache[V]) UseBudget(budget *CacheBudget) {
+	c.budget.Store(budget)
+	c.Clear()
+}
+
+// Clear drops all cached values by swapping in a new generation.
+func (c *cache[V]) Clear() {
+	c.gen.Swap(c.newGen()).discard()
+}
@@ -0,0 +1,265 @@
+package got
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_LoadStore(t *testing.T) {
+	c := newCache[int](nil, nil)
+
+	_, ok := c.Load("a")
+	assert.False(t, ok)
+
+	c.Store("a", 1)
+	c.Store("b", 2)
+
+	v, ok := c.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestCache_Clear(t *testing.T) {
+	c := newCache[int](nil, nil)
+	for i := 0; i < 100; i++ {
+		c.Store(strconv.Itoa(i), i)
+	}
+	assert.Equal(t, 100, c.Len())
+
+	c.Clear()
+
+	assert.Equal(t, 0, c.Len())
+	_, ok := c.Load("1")
+	assert.False(t, ok)
+}
+
+func TestCache_LoadOrBuild(t *testing.T) {
+	c := newCache[int](nil, nil)
+
+	v, err := c.LoadOrBuild("a", func() (int, error) { return 1, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	v, err = c.LoadOrBuild("a", func() (int, error) {
+		t.Fatal("build should not be called for a cached key")
+		return 0, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestCache_LoadOrBuild_ErrorNotCached(t *testing.T) {
+	c := newCache[int](nil, nil)
+	buildErr := errors.New("build failed")
+
+	_, err := c.LoadOrBuild("a", func() (int, error) { return 0, buildErr })
+	assert.ErrorIs(t, err, buildErr)
+
+	_, ok := c.Load("a")
+	assert.False(t, ok)
+}
+
+func TestCache_LoadOrBuild_Panic(t *testing.T) {
+	c := newCache[int](nil, nil)
+
+	assert.Panics(t, func() {
+		_, _ = c.LoadOrBuild("a", func() (int, error) { panic("boom") })
+	})
+
+	_, ok := c.Load("a")
+	assert.False(t, ok)
+
+	v, err := c.LoadOrBuild("a", func() (int, error) { return 2, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
+
+func TestCache_LoadOrBuild_Coalesces(t *testing.T) {
+	c := newCache[int](nil, nil)
+
+	var calls atomic.Int32
+	building, release := make(chan struct{}), make(chan struct{})
+	build := func() (int, error) {
+		if calls.Add(1) == 1 {
+			close(building)
+		}
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		v, err := c.LoadOrBuild("a", build)
+		assert.NoError(t, err)
+		assert.Equal(t, 42, v)
+	})
+
+	// while the first build is held, the other callers can only wait for it
+	<-building
+	for range 9 {
+		wg.Go(func() {
+			v, err := c.LoadOrBuild("a", build)
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v)
+		})
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+
+	v, ok := c.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 42, v)
+}
+
+func TestCache_ClearDuringBuild(t *testing.T) {
+	c := newCache[int](nil, nil)
+
+	v, err := c.LoadOrBuild("a", func() (int, error) {
+		c.Clear()
+		return 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	_, ok := c.Load("a")
+	assert.False(t, ok, "values built before Clear belong to the old generation")
+}
+
+func TestCache_Size(t *testing.T) {
+	c := newCache(nil, func(v int) int64 { return int64(v) })
+
+	c.Store("a", 10)
+	c.Store("b", 20)
+	assert.Equal(t, int64(30), c.Size())
+
+	c.Store("a", 5)
+	assert.Equal(t, int64(25), c.Size())
+
+	c.Clear()
+	assert.Equal(t, int64(0), c.Size())
+}
+
+func TestCache_Budget(t *testing.T) {
+	c := newCache(nil, func(v int) int64 { return int64(v) })
+	c.SetBudget(25)
+	assert.Equal(t, int64(25), c.Budget())
+
+	c.Store("a", 10)
+	c.Store("b", 10)
+
+	// touch "a" so "b" becomes the least recently used entry
+	_, ok := c.Load("a")
+	require.True(t, ok)
+
+	c.Store("c", 10)
+
+	assert.LessOrEqual(t, c.Size(), int64(25))
+	_, ok = c.Load("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = c.Load("a")
+	assert.True(t, ok)
+	_, ok = c.Load("c")
+	assert.True(t, ok)
+}
+
+func TestCache_SetBudget_Evicts(t *testing.T) {
+	c := newCache(nil, func(v int) int64 { return int64(v) })
+	for i := 0; i < 10; i++ {
+		c.Store(strconv.Itoa(i), 10)
+	}
+	assert.Equal(t, int64(100), c.Size())
+
+	c.SetBudget(35)
+	assert.Equal(t, int64(30), c.Size())
+	assert.Equal(t, 3, c.Len())
+
+	c.SetBudget(-1)
+	assert.Equal(t, int64(0), c.Budget())
+}
+
+func TestCacheBudget_Shared(t *testing.T) {
+	budget := NewCacheBudget(30)
+	a := newCache(budget, func(v int) int64 { return int64(v) })
+	b := newCache(budget, func(v int) int64 { return int64(v) })
+
+	a.Store("1", 10)
+	b.Store("1", 10)
+	a.Store("2", 10)
+	assert.Equal(t, int64(30), budget.Size())
+
+	// touch the first entry of a, so the entry of b is the least recently used
+	_, ok := a.Load("1")
+	require.True(t, ok)
+
+	b.Store("2", 10)
+	assert.Equal(t, int64(30), budget.Size())
+	_, ok = b.Load("1")
+	assert.False(t, ok, "least recently used entry of any cache should be evicted")
+	assert.Equal(t, 2, a.Len())
+	assert.Equal(t, int64(10), b.Size())
+
+	// cleared entries leave the budget
+	a.Clear()
+	assert.Equal(t, int64(10), budget.Size())
+
+	b.Delete("2")
+	assert.Equal(t, int64(0), budget.Size())
+}
+
+func TestCacheBudget_Concurrent(t *testing.T) {
+	budget := NewCacheBudget(100)
+	caches := []*cache[int]{
+		newCache(budget, func(v int) int64 { return int64(v) }),
+		newCache(budget, func(v int) int64 { return int64(v) }),
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			c := caches[i%2]
+			for j := range 200 {
+				key := strconv.Itoa(j % 20)
+				c.Store(key, 10)
+				c.Load(key)
+				if j%50 == 0 {
+					c.Clear()
+				}
+				if j%7 == 0 {
+					c.Delete(key)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, budget.Size(), int64(100))
+	assert.Equal(t, budget.Size(), caches[0].Size()+caches[1].Size())
+}
+
+func TestCache_Removed(t *testing.T) {
+	c := newCache(NewCacheBudget(20), func(v int) int64 { return int64(v) })
+
+	var removed []string
+	c.removed = func(key string, value int) {
+		removed = append(removed, key+"="+strconv.Itoa(value))
+	}
+
+	c.Store("a", 10)
+	c.Store("a", 5)
+	c.Store("b", 10)
+	c.Store("c", 10)
+	c.Delete("b")
+	c.DeleteFunc("c", func(v int) bool { return v > 10 })
+	c.Clear()
+
+	assert.Equal(t, []string{"a=10", "a=5", "b=10", "c=10"}, removed)
+}
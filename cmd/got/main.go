@@ -0,0 +1,140 @@
+// Command got provides tooling for themes stored in a directory, one
+// subdirectory per theme.
+//
+// Usage:
+//
+//	got diff [-dir themes] [-json] <theme> <parent> [ancestors...]
+//
+// The diff subcommand reports which templates of a theme override, add or
+// copy unchanged the templates of its parent and ancestors, and which
+// reference layouts or partials that no longer exist. It exits with status
+// 1 when orphaned references are found, so it can guard theme upgrades in
+// CI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gowool/got"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: got <command> [arguments]
+
+commands:
+  diff  compare a theme with its parent
+`
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "diff":
+		return runDiff(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "got: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runDiff(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dir := flags.String("dir", ".", "directory of the themes, one subdirectory per theme")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: got diff [-dir themes] [-json] <theme> <parent> [ancestors...]")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+
+	// each theme is the parent of the previous one
+	fsys := os.DirFS(*dir)
+	store := got.NewStoreFS(fsys)
+	var theme, child *got.Theme
+	for _, name := range flags.Args() {
+		if info, err := fs.Stat(fsys, name); err != nil || !info.IsDir() {
+			fmt.Fprintf(stderr, "got: theme %s not found in %s\n", name, *dir)
+			return 1
+		}
+
+		t := got.NewTheme(name, store)
+		if child != nil {
+			child.SetParent(t)
+		} else {
+			theme = t
+		}
+		child = t
+	}
+
+	diff, err := theme.Diff(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "got: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(diff); err != nil {
+			fmt.Fprintf(stderr, "got: %v\n", err)
+			return 1
+		}
+	} else {
+		printDiff(stdout, diff)
+	}
+
+	if len(diff.Orphaned) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printDiff(w io.Writer, diff got.ThemeDiff) {
+	fmt.Fprintf(w, "%s compared with %s\n", diff.Theme, diff.Parent)
+
+	section := func(title string, names []string) {
+		fmt.Fprintf(w, "\n%s (%d)\n", title, len(names))
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+	}
+	section("overridden", diff.Overridden)
+	section("identical", diff.Identical)
+	section("added", diff.Added)
+	section("inherited", diff.Inherited)
+
+	fmt.Fprintf(w, "\norphaned (%d)\n", len(diff.Orphaned))
+	for _, name := range slices.Sorted(maps.Keys(diff.Orphaned)) {
+		fmt.Fprintf(w, "  %s -> %s\n", name, strings.Join(diff.Orphaned[name], ", "))
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gowool/got"
+)
+
+func writeThemes(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestRun_Diff(t *testing.T) {
+	dir := writeThemes(t, map[string]string{
+		"base/layouts/base.html":  `<html>{{block "content" .}}{{end}}</html>`,
+		"base/partials/nav.html":  `<nav></nav>`,
+		"base/index.html":         "<!-- layouts/base.html -->\n" + `{{define "content"}}{{end}}`,
+		"child/partials/nav.html": `<nav class="custom"></nav>`,
+		"child/about.html":        "<!-- layouts/base.html -->\n" + `{{define "content"}}{{end}}`,
+	})
+
+	var stdout, stderr strings.Builder
+	code := run(context.Background(), []string{"diff", "-dir", dir, "child", "base"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `child compared with base
+
+overridden (1)
+  partials/nav.html
+
+identical (0)
+
+added (1)
+  about.html
+
+inherited (2)
+  index.html
+  layouts/base.html
+
+orphaned (0)
+`, stdout.String())
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"diff", "-dir", dir, "-json", "child", "base"}, &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	var diff got.ThemeDiff
+	require.NoError(t, json.Unmarshal([]byte(stdout.String()), &diff))
+	assert.Equal(t, []string{"partials/nav.html"}, diff.Overridden)
+}
+
+func TestRun_Diff_Orphaned(t *testing.T) {
+	dir := writeThemes(t, map[string]string{
+		"grand/layouts/base.html": `<html>{{block "content" .}}{{end}}</html>`,
+		"base/index.html":         "<!-- layouts/base.html -->\n" + `{{define "content"}}{{end}}`,
+		"child/landing.html":      "<!-- layouts/landing.html -->\n" + `{{define "content"}}{{end}}`,
+	})
+
+	var stdout, stderr strings.Builder
+	code := run(context.Background(), []string{"diff", "-dir", dir, "child", "base", "grand"}, &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "orphaned (1)\n  landing.html -> layouts/landing.html\n")
+	assert.Contains(t, stdout.String(), "  layouts/base.html\n")
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		err  string
+	}{
+		{"no command", nil, 2, "usage: got <command>"},
+		{"unknown command", []string{"build"}, 2, `got: unknown command "build"`},
+		{"missing parent", []string{"diff", "child"}, 2, "usage: got diff"},
+		{"bad flag", []string{"diff", "-x"}, 2, "flag provided but not defined"},
+		{"missing theme", []string{"diff", "-dir", t.TempDir(), "child", "base"}, 1, "got: theme child not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			assert.Equal(t, tt.code, run(context.Background(), tt.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package got
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// builtinTheme is the name of the theme holding the built-in components.
+const builtinTheme = "builtin"
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// builtin serves the built-in component templates, such as
+// "components/pagination.html", to every theme that does not define a
+// template of the same name itself.
+var builtin = NewTheme(builtinTheme, NewStoreFS(builtinFS))
+
+// Components returns the names of the built-in component templates.
+func Components() []string {
+	var names []string
+	_ = fs.WalkDir(builtinFS, builtinTheme, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			names = append(names, path[len(builtinTheme)+1:])
+		}
+		return err
+	})
+	return names
+}
@@ -0,0 +1,50 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComponents(t *testing.T) {
+	assert.Contains(t, Components(), "components/pagination.html")
+}
+
+func TestTheme_BuiltinPagination(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "list", `<main>{{template "components/pagination.html" (paginate .Total 10 .Page "/posts")}}</main>`)
+
+	theme := NewTheme("test", store)
+	theme.SetFuncMap(Funcs)
+
+	var buf bytes.Buffer
+	err := theme.Write(context.Background(), &buf, "list", map[string]any{"Total": 30, "Page": 2})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, `<nav class="pagination" aria-label="Pagination">`)
+	assert.Contains(t, out, `<a href="/posts" rel="prev" aria-label="Previous page">&laquo;</a>`)
+	assert.Contains(t, out, `<a href="/posts?page=2" aria-current="page">2</a>`)
+	assert.Contains(t, out, `<a href="/posts?page=3" rel="next" aria-label="Next page">&raquo;</a>`)
+
+	buf.Reset()
+	err = theme.Write(context.Background(), &buf, "list", map[string]any{"Total": 5, "Page": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "<main></main>", buf.String())
+}
+
+func TestTheme_OverrideBuiltinComponent(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "list", `{{template "components/pagination.html" (paginate 30 10 2)}}`)
+	store.Add("test", "components/pagination.html", `page {{.Page}} of {{.Pages}}`)
+
+	theme := NewTheme("test", store)
+	theme.SetFuncMap(Funcs)
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.Write(context.Background(), &buf, "list", nil))
+	assert.Equal(t, "page 2 of 3", buf.String())
+}
@@ -0,0 +1,148 @@
+package got
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+	"strings"
+)
+
+// ContentSecurityPolicy returns script-src and style-src directives
+// allowing the inline blocks of the page next to the given sources:
+//
+//	w.Header().Set("Content-Security-Policy", result.ContentSecurityPolicy("'self'"))
+func (r RenderResult) ContentSecurityPolicy(sources ...string) string {
+	directive := func(name string, hashes []string) string {
+		return strings.Join(slices.Concat([]string{name}, sources, hashes), " ")
+	}
+	return directive("script-src", r.ScriptHashes) + "; " + directive("style-src", r.StyleHashes)
+}
+
+// cspHashes returns the hash sources of the inline scripts and styles of
+// an HTML document. Scripts with a src attribute are external and skipped.
+//
+// Tags are matched ignoring ASCII case on the document itself, since
+// Unicode case mapping may change the length of the text before them.
+func cspHashes(out []byte) (scripts, styles []string) {
+	for _, tag := range []string{"script", "style"} {
+		open := "<" + tag
+		end := "</" + tag
+
+		for i := 0; ; {
+			j := indexFold(out[i:], open)
+			if j < 0 {
+				break
+			}
+			i += j + len(open)
+
+			if i < len(out) && !isTagNameEnd(out[i]) {
+				continue
+			}
+
+			attrs, ok := tagAttrs(out[i:])
+			if !ok {
+				break
+			}
+			i += len(attrs) + 1
+
+			k := indexFold(out[i:], end)
+			if k < 0 {
+				break
+			}
+			content := out[i : i+k]
+			i += k
+
+			if tag == "script" && hasAttr(attrs, "src") {
+				continue
+			}
+
+			sum := sha256.Sum256(content)
+			hash := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+
+			if tag == "script" {
+				if !slices.Contains(scripts, hash) {
+					scripts = append(scripts, hash)
+				}
+			} else if !slices.Contains(styles, hash) {
+				styles = append(styles, hash)
+			}
+		}
+	}
+
+	return scripts, styles
+}
+
+func isTagNameEnd(c byte) bool {
+	return c == '>' || c == '/' || c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// tagAttrs returns the attributes of a start tag up to the closing '>',
+// skipping quoted values.
+func tagAttrs(b []byte) ([]byte, bool) {
+	var quote byte
+	for i, c := range b {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return b[:i], true
+		}
+	}
+	return nil, false
+}
+
+// hasAttr reports whether the attributes contain the named one, given in
+// lower case.
+func hasAttr(attrs []byte, name string) bool {
+	var quote byte
+	for i := 0; i < len(attrs); i++ {
+		c := attrs[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case hasPrefixFold(attrs[i:], name) && (i == 0 || isTagNameEnd(attrs[i-1])):
+			rest := attrs[i+len(name):]
+			if len(rest) == 0 || rest[0] == '=' || isTagNameEnd(rest[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// indexFold returns the index of the first instance of the lower case ASCII
+// substr in b ignoring ASCII case, or -1.
+func indexFold(b []byte, substr string) int {
+	for i := 0; i+len(substr) <= len(b); i++ {
+		if hasPrefixFold(b[i:], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasPrefixFold reports whether b begins with the lower case ASCII prefix,
+// ignoring ASCII case.
+func hasPrefixFold(b []byte, prefix string) bool {
+	if len(b) < len(prefix) {
+		return false
+	}
+	for i := range len(prefix) {
+		c := b[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cspHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+func TestCSPHashes(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		scripts []string
+		styles  []string
+	}{
+		{
+			name: "none",
+			out:  "<p>plain</p>",
+		},
+		{
+			name:    "inline blocks",
+			out:     `<style>p{color:red}</style><SCRIPT type="module">alert(1)</SCRIPT><script>alert(2)</script>`,
+			scripts: []string{cspHash("alert(1)"), cspHash("alert(2)")},
+			styles:  []string{cspHash("p{color:red}")},
+		},
+		{
+			name:    "external and duplicate scripts",
+			out:     `<script src="/app.js"></script><script data-src="x">a()</script><script>a()</script>`,
+			scripts: []string{cspHash("a()")},
+		},
+		{
+			name:    "quoted attributes",
+			out:     `<script data-x="a > b" title='src'>b()</script>`,
+			scripts: []string{cspHash("b()")},
+		},
+		{
+			name:    "non-ASCII text changing length when lowercased",
+			out:     "<p>İstanbul \u212a Ⱥ</p><script>c()</script><style>i{}</style>",
+			scripts: []string{cspHash("c()")},
+			styles:  []string{cspHash("i{}")},
+		},
+		{
+			name: "upper case src attribute",
+			out:  `<script SRC="/app.js"></script>`,
+		},
+		{
+			name: "not a script tag",
+			out:  `<scripts>x</scripts><stylesheet>y</stylesheet>`,
+		},
+		{
+			name: "unterminated",
+			out:  `<script>x()`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripts, styles := cspHashes([]byte(tt.out))
+			assert.Equal(t, tt.scripts, scripts)
+			assert.Equal(t, tt.styles, styles)
+		})
+	}
+}
+
+func TestRenderResult_ContentSecurityPolicy(t *testing.T) {
+	result := RenderResult{ScriptHashes: []string{"'sha256-a'"}}
+	assert.Equal(t, "script-src 'self' 'sha256-a'; style-src 'self'", result.ContentSecurityPolicy("'self'"))
+	assert.Equal(t, "script-src; style-src", RenderResult{}.ContentSecurityPolicy())
+}
+
+func TestTheme_Render_CSPHashes(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<style>h1{color:red}</style><h1>{{.}}</h1><script>var x = {{.}};</script>`)
+
+	theme := NewTheme("test", store)
+	assert.False(t, theme.CSPHashes())
+
+	var buf bytes.Buffer
+	result, err := theme.Render(context.Background(), &buf, "page", "hi")
+	require.NoError(t, err)
+	assert.Empty(t, result.ScriptHashes)
+	assert.Empty(t, result.StyleHashes)
+
+	theme.SetCSPHashes(true)
+	assert.True(t, theme.CSPHashes())
+
+	buf.Reset()
+	result, err = theme.Render(context.Background(), &buf, "page", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, `<style>h1{color:red}</style><h1>hi</h1><script>var x = "hi";</script>`, buf.String())
+	assert.Equal(t, []string{cspHash(`var x = "hi";`)}, result.ScriptHashes)
+	assert.Equal(t, []string{cspHash("h1{color:red}")}, result.StyleHashes)
+}
@@ -0,0 +1,53 @@
+package got
+
+import (
+	"html/template"
+	"reflect"
+)
+
+// deprecatedFuncs returns aliases for the deprecated function names of the
+// theme. Every alias calls its replacement from funcMap and logs a warning
+// the first time it is used. Aliases whose replacement is missing from
+// funcMap are skipped.
+func (t *Theme) deprecatedFuncs(funcMap template.FuncMap) template.FuncMap {
+	funcs := make(template.FuncMap)
+
+	t.deprecated.Range(func(key, value any) bool {
+		alias, name := key.(string), value.(string)
+
+		fn, ok := funcMap[name]
+		if !ok {
+			return true
+		}
+
+		v := reflect.ValueOf(fn)
+		if v.Kind() != reflect.Func {
+			return true
+		}
+
+		funcs[alias] = reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+			t.warnDeprecated(alias, name)
+
+			if v.Type().IsVariadic() {
+				return v.CallSlice(args)
+			}
+			return v.Call(args)
+		}).Interface()
+
+		return true
+	})
+
+	return funcs
+}
+
+func (t *Theme) warnDeprecated(alias, name string) {
+	if _, warned := t.warned.LoadOrStore(alias, struct{}{}); warned {
+		return
+	}
+
+	t.Logger().Warn("got: deprecated template function",
+		"theme", t.name,
+		"func", alias,
+		"replacement", name,
+	)
+}
@@ -0,0 +1,70 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme_AddDeprecatedFuncs(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{upcase .}} {{concat_all "a" "b" "c"}} {{upcase .}}`)
+
+	var logs bytes.Buffer
+	theme := NewTheme("test", store)
+	theme.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	theme.AddFuncMap(template.FuncMap{
+		"str_upper": strings.ToUpper,
+		"str_build": Funcs["str_build"],
+	})
+	theme.AddDeprecatedFuncs(map[string]string{
+		"upcase":     "str_upper",
+		"concat_all": "str_build",
+		"missing":    "str_missing",
+	})
+
+	assert.Equal(t, map[string]string{
+		"upcase":     "str_upper",
+		"concat_all": "str_build",
+		"missing":    "str_missing",
+	}, theme.DeprecatedFuncs())
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		var buf strings.Builder
+		require.NoError(t, theme.Write(ctx, &buf, "page", "hi"))
+		assert.Equal(t, "HI abc HI", buf.String())
+	}
+
+	assert.Equal(t, 1, strings.Count(logs.String(), "func=upcase"))
+	assert.Equal(t, 1, strings.Count(logs.String(), "func=concat_all"))
+	assert.Contains(t, logs.String(), "replacement=str_upper")
+	assert.Contains(t, logs.String(), "level=WARN")
+}
+
+func TestTheme_AddDeprecatedFuncs_MissingReplacement(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{missing}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddDeprecatedFuncs(map[string]string{"missing": "str_missing"})
+
+	err := theme.Write(context.Background(), &strings.Builder{}, "page", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `function "missing" not defined`)
+}
+
+func TestTheme_Logger(t *testing.T) {
+	theme := NewTheme("test", NewStoreMemory())
+	assert.Same(t, slog.Default(), theme.Logger())
+
+	logger := slog.New(slog.DiscardHandler)
+	theme.SetLogger(logger)
+	assert.Same(t, logger, theme.Logger())
+}
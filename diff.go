@@ -0,0 +1,143 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrNoParent is returned when comparing a theme without a parent.
+var ErrNoParent = errors.New("theme has no parent")
+
+// ThemeDiff compares the templates of a theme with the ones of its parent,
+// see Theme.Diff. Template names are sorted.
+type ThemeDiff struct {
+	Theme  string
+	Parent string
+
+	// Overridden are the templates of the theme replacing a different
+	// template of the parent.
+	Overridden []string
+
+	// Identical are the templates of the theme identical to the template of
+	// the parent they replace.
+	Identical []string
+
+	// Added are the templates of the theme the parent doesn't have.
+	Added []string
+
+	// Inherited are the templates of the parent the theme doesn't replace.
+	Inherited []string
+
+	// Orphaned maps the templates of the theme to the layouts and templates
+	// they reference that neither the theme nor its parent provide, such as
+	// ones removed from an upgraded parent.
+	Orphaned map[string][]string
+}
+
+// Diff compares the templates of the theme with the ones of its parent and
+// ancestors, which is useful to review the customizations of a child theme
+// when upgrading the theme underneath. The stores of the theme and its
+// ancestors must implement Lister, Diff fails with ErrNotLister otherwise.
+func (t *Theme) Diff(ctx context.Context) (ThemeDiff, error) {
+	parent := t.Parent()
+	if parent == nil {
+		return ThemeDiff{}, fmt.Errorf("theme: failed to diff %s: %w", t.name, ErrNoParent)
+	}
+
+	diff := ThemeDiff{Theme: t.name, Parent: parent.name, Orphaned: make(map[string][]string)}
+
+	lister, ok := t.store.(Lister)
+	if !ok {
+		return diff, fmt.Errorf("theme: failed to diff %s: %w", t.name, ErrNotLister)
+	}
+	names, err := lister.List(ctx, t.name)
+	if err != nil {
+		return diff, fmt.Errorf("theme: failed to list templates of %s: %w", t.name, err)
+	}
+	slices.Sort(names)
+
+	inherited, err := parent.list(ctx)
+	if err != nil {
+		return diff, err
+	}
+
+	own := make(map[string]Template, len(names))
+	for _, name := range names {
+		item, err := t.store.Find(ctx, t.name, name)
+		if err != nil {
+			return diff, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
+		}
+		own[name] = item
+	}
+
+	// every template and {{define}} the pages of the theme can resolve
+	funcs := t.buildFuncs()
+	known := make(map[string]struct{})
+	deps := make(map[string]*dependency, len(names))
+	for _, name := range slices.Concat(names, inherited, Components()) {
+		item, ok := own[name]
+		if !ok {
+			if item, err = parent.find(ctx, name); err != nil {
+				return diff, err
+			}
+		}
+
+		p, err := t.parseTrees(item, funcs)
+		if err != nil {
+			return diff, err
+		}
+
+		known[name] = struct{}{}
+		for _, defined := range p.defined {
+			known[defined] = struct{}{}
+		}
+		if _, ok := own[name]; ok {
+			deps[name] = &dependency{Template: item, parsed: p}
+		}
+	}
+
+	for _, name := range inherited {
+		item, ok := own[name]
+		if !ok {
+			diff.Inherited = append(diff.Inherited, name)
+			continue
+		}
+
+		base, err := parent.find(ctx, name)
+		if err != nil {
+			return diff, err
+		}
+		if base.Path() == item.Path() && base.Content() == item.Content() {
+			diff.Identical = append(diff.Identical, name)
+		} else {
+			diff.Overridden = append(diff.Overridden, name)
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := slices.BinarySearch(inherited, name); !ok {
+			diff.Added = append(diff.Added, name)
+		}
+
+		dep := deps[name]
+
+		var missing []string
+		if dep.Path() != dep.Name() {
+			if _, ok := known[dep.Path()]; !ok {
+				missing = append(missing, dep.Path())
+			}
+		}
+		for _, include := range dep.includes() {
+			if _, ok := known[include]; !ok && !slices.Contains(missing, include) {
+				missing = append(missing, include)
+			}
+		}
+		if len(missing) > 0 {
+			diff.Orphaned[name] = missing
+		}
+	}
+
+	return diff, nil
+}
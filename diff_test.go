@@ -0,0 +1,64 @@
+package got
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme_Diff(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("base", "layouts/base.html", `<html>{{block "content" .}}{{end}}</html>`)
+	store.Add("base", "partials/nav.html", `<nav></nav>`)
+	store.Add("base", "partials/footer.html", `<footer></footer>`)
+	store.Add("base", "index.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{template "partials/nav.html"}}{{end}}`)
+
+	store.Add("child", "partials/nav.html", `<nav class="custom"></nav>`)
+	store.Add("child", "partials/footer.html", `<footer></footer>`)
+	store.Add("child", "about.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{template "partials/sidebar.html"}}{{template "components/pagination.html"}}{{end}}`)
+	store.Add("child", "landing.html", "<!-- layouts/landing.html -->\n"+`{{define "content"}}{{end}}`)
+
+	parent := NewTheme("base", store)
+	child := NewTheme("child", store)
+
+	_, err := child.Diff(context.Background())
+	require.ErrorIs(t, err, ErrNoParent)
+
+	child.SetParent(parent)
+
+	diff, err := child.Diff(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ThemeDiff{
+		Theme:      "child",
+		Parent:     "base",
+		Overridden: []string{"partials/nav.html"},
+		Identical:  []string{"partials/footer.html"},
+		Added:      []string{"about.html", "landing.html"},
+		Inherited:  []string{"index.html", "layouts/base.html"},
+		Orphaned: map[string][]string{
+			"about.html":   {"partials/sidebar.html"},
+			"landing.html": {"layouts/landing.html"},
+		},
+	}, diff)
+}
+
+func TestTheme_Diff_NotLister(t *testing.T) {
+	store := &MockStore{}
+	child := NewTheme("child", store)
+	child.SetParent(NewTheme("base", store))
+
+	_, err := child.Diff(context.Background())
+	assert.EqualError(t, err, "theme: failed to diff child: store doesn't list templates")
+	assert.ErrorIs(t, err, ErrNotLister)
+
+	// nor the parent's, whose templates would all look added
+	store = &MockStore{}
+	child = NewTheme("child", NewStoreMemory())
+	child.SetParent(NewTheme("base", store))
+
+	_, err = child.Diff(context.Background())
+	assert.EqualError(t, err, "theme: failed to list templates of base: store doesn't list templates")
+	assert.ErrorIs(t, err, ErrNotLister)
+}
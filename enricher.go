@@ -0,0 +1,53 @@
+package got
+
+import "context"
+
+// DataEnricher wraps or augments the data of every render of a theme, e.g.
+// to inject the current user, permissions or feature flags from the request
+// context.
+type DataEnricher interface {
+	Enrich(ctx context.Context, data any) (any, error)
+}
+
+// DataEnricherFunc is an adapter to use ordinary functions as DataEnricher.
+type DataEnricherFunc func(ctx context.Context, data any) (any, error)
+
+func (f DataEnricherFunc) Enrich(ctx context.Context, data any) (any, error) {
+	return f(ctx, data)
+}
+
+// DataEnrichers returns the data enrichers of the theme.
+func (t *Theme) DataEnrichers() []DataEnricher {
+	if enrichers := t.enrichers.Load(); enrichers != nil {
+		return *enrichers
+	}
+	return nil
+}
+
+// AddDataEnricher registers enrichers applied, in order, to the data of every
+// render of the theme.
+func (t *Theme) AddDataEnricher(enrichers ...DataEnricher) {
+	for {
+		old := t.enrichers.Load()
+
+		var list []DataEnricher
+		if old != nil {
+			list = append(list, *old...)
+		}
+		list = append(list, enrichers...)
+
+		if t.enrichers.CompareAndSwap(old, &list) {
+			return
+		}
+	}
+}
+
+func (t *Theme) enrich(ctx context.Context, data any) (any, error) {
+	var err error
+	for _, enricher := range t.DataEnrichers() {
+		if data, err = enricher.Enrich(ctx, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
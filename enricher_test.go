@@ -0,0 +1,54 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type userKey struct{}
+
+func TestTheme_AddDataEnricher(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{.User}}:{{.Page}}:{{.Flag}}`)
+
+	theme := NewTheme("test", store)
+	assert.Empty(t, theme.DataEnrichers())
+
+	theme.AddDataEnricher(DataEnricherFunc(func(ctx context.Context, data any) (any, error) {
+		return map[string]any{"User": ctx.Value(userKey{}), "Page": data}, nil
+	}))
+	theme.AddDataEnricher(DataEnricherFunc(func(_ context.Context, data any) (any, error) {
+		m := data.(map[string]any)
+		m["Flag"] = true
+		return m, nil
+	}))
+	assert.Len(t, theme.DataEnrichers(), 2)
+
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", "home"))
+	assert.Equal(t, "alice:home:true", buf.String())
+}
+
+func TestTheme_AddDataEnricher_Error(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{.}}`)
+
+	enrichErr := errors.New("no session")
+	theme := NewTheme("test", store)
+	theme.AddDataEnricher(DataEnricherFunc(func(context.Context, any) (any, error) {
+		return nil, enrichErr
+	}))
+
+	var buf strings.Builder
+	err := theme.Write(context.Background(), &buf, "page", nil)
+	assert.ErrorIs(t, err, enrichErr)
+	assert.Contains(t, err.Error(), "failed to enrich data of template test/page")
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,295 @@
+package got
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+var (
+	execLocationRe  = regexp.MustCompile(`^template: (.*?):(\d+):(\d+): executing `)
+	parseLocationRe = regexp.MustCompile(`^template: (.*?):(\d+):(?:(\d+):)? `)
+)
+
+// excerptContext is the number of lines shown around the offending line of a
+// ParseError excerpt.
+const excerptContext = 2
+
+// ParseError is returned when a template fails to parse. In debug mode it
+// carries an excerpt of the offending lines of the source.
+type ParseError struct {
+	// Source is the name of the store template that failed to parse.
+	Source string
+	Line   int
+	// Column is zero when the parser doesn't report it.
+	Column  int
+	Excerpt string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	if e.Excerpt == "" {
+		return e.Err.Error()
+	}
+	return e.Err.Error() + "\n" + e.Excerpt
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func newParseError(source Template, err error, excerpt bool) *ParseError {
+	e := &ParseError{Source: source.Name(), Err: err}
+
+	if m := parseLocationRe.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[2])
+		e.Column, _ = strconv.Atoi(m[3])
+	}
+
+	if excerpt && e.Line > 0 {
+		e.Excerpt = sourceExcerpt(source.Content(), e.Line, e.Column)
+	}
+
+	return e
+}
+
+// sourceExcerpt formats the lines around line, marking it and placing a caret
+// under column when it is known.
+func sourceExcerpt(content string, line, column int) string {
+	lines := strings.Split(content, "\n")
+	if line > len(lines) {
+		return ""
+	}
+
+	first := max(line-excerptContext, 1)
+	last := min(line+excerptContext, len(lines))
+	width := len(strconv.Itoa(last))
+
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i, lines[i-1])
+
+		if i == line && column > 0 {
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", strings.Repeat(" ", column))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ExecError is returned by Theme.Write when a page fails to execute. Stack
+// holds the inclusion chain from the executed page down to the template that
+// failed, e.g. page → layout → partial.
+//
+// Only the include calls of the chain are recorded during the execution,
+// along with the templates they executed. The {{template}} calls between
+// them are inferred from the parse trees: the chain is the shortest chain of
+// {{template}} calls leading to the include call or to the failing template.
+// When one of them can be reached by other chains, or by other calls of the
+// chain, the executed chain may differ and Guessed is set.
+type ExecError struct {
+	Theme   string
+	Name    string
+	Stack   []Frame
+	Guessed bool
+	Err     error
+}
+
+// Frame is an entry of the inclusion stack of an ExecError.
+//
+// Line and Column locate the {{template}} or include call to the next frame,
+// or the failing action for the last frame.
+type Frame struct {
+	// Name is the name of the executed template.
+	Name string
+	// Source is the name of the store template declaring it.
+	Source string
+	Line   int
+	Column int
+}
+
+func (f Frame) String() string {
+	if f.Line == 0 {
+		return f.Name
+	}
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Name, f.Source, f.Line, f.Column)
+}
+
+func (e *ExecError) Error() string {
+	if len(e.Stack) == 0 {
+		return fmt.Sprintf("theme: failed to execute template %s/%s: %v", e.Theme, e.Name, e.Err)
+	}
+
+	frames := make([]string, len(e.Stack))
+	for i, frame := range e.Stack {
+		frames[i] = frame.String()
+	}
+	stack := strings.Join(frames, " → ")
+	if e.Guessed {
+		stack += " (best guess)"
+	}
+	return fmt.Sprintf("theme: failed to execute template %s/%s [%s]: %v", e.Theme, e.Name, stack, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+func newExecError(theme, name string, tpl *template.Template, err error) *ExecError {
+	e := &ExecError{Theme: theme, Name: name, Err: err}
+
+	// every failed include nests the error of the template it executed, the
+	// chain is followed from one to the next
+	root := tpl.Name()
+	for {
+		var execErr texttemplate.ExecError
+		if !errors.As(err, &execErr) {
+			return e
+		}
+
+		// html/template executes contextual variants of templates under
+		// derived names, e.g. "name$htmltemplate_stateJS"
+		target, _, _ := strings.Cut(execErr.Name, "$htmltemplate_")
+
+		stack, guessed := inclusionStack(tpl, root, target)
+		if len(stack) == 0 {
+			return e
+		}
+
+		if m := execLocationRe.FindStringSubmatch(execErr.Error()); m != nil {
+			last := &stack[len(stack)-1]
+			last.Source = m[1]
+			last.Line, _ = strconv.Atoi(m[2])
+			last.Column, _ = strconv.Atoi(m[3])
+		}
+		e.Stack = append(e.Stack, stack...)
+		e.Guessed = e.Guessed || guessed
+
+		var include *includeError
+		if !errors.As(execErr.Err, &include) {
+			return e
+		}
+		root, err = include.name, include.err
+	}
+}
+
+// inclusionStack finds the shortest chain of {{template}} calls from the
+// template named root to the template named target. It reports whether the
+// chain is a guess, a template of the chain being called more than once by
+// the templates reachable from the root.
+func inclusionStack(tpl *template.Template, root, target string) ([]Frame, bool) {
+	type call struct {
+		from  string
+		frame Frame
+	}
+
+	trees := make(map[string]*parse.Tree)
+	for _, item := range tpl.Templates() {
+		if item.Tree != nil {
+			trees[item.Name()] = item.Tree
+		}
+	}
+
+	if _, ok := trees[root]; !ok {
+		return nil, false
+	}
+
+	visited := map[string]*call{root: nil}
+	queue := []string{root}
+
+	// sites counts the calls of each template, so the whole reachable set is
+	// walked
+	sites := make(map[string]int)
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		tree := trees[name]
+		walkTemplateNodes(tree.Root, func(node *parse.TemplateNode) {
+			if _, ok := trees[node.Name]; !ok {
+				return
+			}
+			sites[node.Name]++
+			if _, ok := visited[node.Name]; ok {
+				return
+			}
+
+			location, _ := tree.ErrorContext(node)
+			frame := Frame{Name: name}
+			frame.Source, frame.Line, frame.Column = splitLocation(location)
+
+			visited[node.Name] = &call{from: name, frame: frame}
+			queue = append(queue, node.Name)
+		})
+	}
+
+	if _, ok := visited[target]; !ok {
+		return nil, false
+	}
+
+	stack := []Frame{{Name: target}}
+	guessed := false
+	for name, c := target, visited[target]; c != nil; name, c = c.from, visited[c.from] {
+		stack = append(stack, c.frame)
+		guessed = guessed || sites[name] > 1
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return stack, guessed
+}
+
+func walkTemplateNodes(node parse.Node, fn func(*parse.TemplateNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			walkTemplateNodes(item, fn)
+		}
+	case *parse.IfNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTemplateNodes(n.List, fn)
+		walkTemplateNodes(n.ElseList, fn)
+	case *parse.TemplateNode:
+		fn(n)
+	}
+}
+
+// splitLocation splits a "source:line:column" location.
+func splitLocation(location string) (source string, line, column int) {
+	rest, col, ok := cutLast(location, ":")
+	if !ok {
+		return location, 0, 0
+	}
+	source, ln, ok := cutLast(rest, ":")
+	if !ok {
+		return location, 0, 0
+	}
+
+	line, _ = strconv.Atoi(ln)
+	column, _ = strconv.Atoi(col)
+	return source, line, column
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,186 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrame_String(t *testing.T) {
+	assert.Equal(t, "content", Frame{Name: "content"}.String())
+	assert.Equal(t, "content (page:2:5)", Frame{Name: "content", Source: "page", Line: 2, Column: 5}.String())
+}
+
+func TestExecError(t *testing.T) {
+	cause := errors.New("boom")
+
+	err := &ExecError{Theme: "test", Name: "page", Err: cause}
+	assert.Equal(t, "theme: failed to execute template test/page: boom", err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	err.Stack = []Frame{
+		{Name: "layout", Source: "layout", Line: 1, Column: 7},
+		{Name: "partial", Source: "partial", Line: 2, Column: 3},
+	}
+	assert.Equal(t, "theme: failed to execute template test/page [layout (layout:1:7) → partial (partial:2:3)]: boom", err.Error())
+
+	err.Guessed = true
+	assert.Equal(t, "theme: failed to execute template test/page [layout (layout:1:7) → partial (partial:2:3) (best guess)]: boom", err.Error())
+}
+
+func TestTheme_Write_ExecErrorStack(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", "<main>\n{{block \"content\" .}}default{{end}}</main>")
+	store.Add("test", "page", "<!-- layouts/base -->{{define \"content\"}}\n{{if .}}{{template \"partials/item\" .}}{{end}}{{end}}")
+	store.Add("test", "partials/item", "<p>\n  {{.Item.Name}}</p>")
+
+	theme := NewTheme("test", store)
+
+	err := theme.Write(context.Background(), io.Discard, "page", map[string]any{"Item": 1})
+	require.Error(t, err)
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, "test", execErr.Theme)
+	assert.Equal(t, "page", execErr.Name)
+	assert.Equal(t, []Frame{
+		{Name: "layouts/base", Source: "layouts/base", Line: 2, Column: 8},
+		{Name: "content", Source: "page", Line: 2, Column: 19},
+		{Name: "partials/item", Source: "partials/item", Line: 2, Column: 9},
+	}, execErr.Stack)
+	assert.False(t, execErr.Guessed)
+	assert.Contains(t, err.Error(), "can't evaluate field Name")
+}
+
+func TestTheme_Write_ExecErrorStackGuessed(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{define "a"}}{{template "partials/item" .A}}{{end}}{{define "b"}}{{template "partials/item" .B}}{{end}}{{template "a" .}}{{template "b" .}}`)
+	store.Add("test", "partials/item", `<p>{{.Name}}</p>`)
+
+	theme := NewTheme("test", store)
+
+	// the partial fails when called by b, the shortest chain goes through a
+	err := theme.Write(context.Background(), io.Discard, "page", map[string]any{
+		"A": map[string]any{"Name": "a"},
+		"B": 1,
+	})
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.True(t, execErr.Guessed)
+	assert.Equal(t, []string{"page", "a", "partials/item"}, []string{execErr.Stack[0].Name, execErr.Stack[1].Name, execErr.Stack[2].Name})
+	assert.Contains(t, err.Error(), "partials/item (partials/item:1:5) (best guess)]")
+}
+
+func TestTheme_Write_ExecErrorStackInclude(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", "<main>\n{{include (printf \"partials/%s\" .Kind) .}}</main>")
+	store.Add("test", "partials/card", `<div>{{template "partials/title" .}}</div>`)
+	store.Add("test", "partials/title", `<h2>{{.Title.Name}}</h2>`)
+
+	theme := NewTheme("test", store)
+	theme.SetEager(true)
+
+	// the included template is only known at execution time
+	err := theme.Write(context.Background(), io.Discard, "page", map[string]any{"Kind": "card", "Title": 1})
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, []Frame{
+		{Name: "page", Source: "page", Line: 2, Column: 2},
+		{Name: "partials/card", Source: "partials/card", Line: 1, Column: 16},
+		{Name: "partials/title", Source: "partials/title", Line: 1, Column: 12},
+	}, execErr.Stack)
+	assert.False(t, execErr.Guessed)
+	assert.Contains(t, err.Error(), "can't evaluate field Name")
+}
+
+func TestTheme_Write_ExecErrorRoot(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", "<p>{{index . 5}}</p>")
+
+	theme := NewTheme("test", store)
+
+	err := theme.Write(context.Background(), io.Discard, "page", []int{1})
+
+	var execErr *ExecError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, []Frame{{Name: "page", Source: "page", Line: 1, Column: 5}}, execErr.Stack)
+}
+
+func TestSplitLocation(t *testing.T) {
+	source, line, column := splitLocation("pages/a:b:3:14")
+	assert.Equal(t, "pages/a:b", source)
+	assert.Equal(t, 3, line)
+	assert.Equal(t, 14, column)
+
+	source, line, column = splitLocation("invalid")
+	assert.Equal(t, "invalid", source)
+	assert.Zero(t, line)
+	assert.Zero(t, column)
+}
+
+func TestTheme_Write_ParseErrorSource(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", "<p>\n{{.Title</p>")
+
+	err := NewTheme("test", store).Write(context.Background(), io.Discard, "page", nil)
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "template: page:2:"), err.Error())
+}
+
+func TestSourceExcerpt(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive\nsix"
+
+	assert.Equal(t, "  1 | one\n  2 | two\n> 3 | three\n    |    ^\n  4 | four\n  5 | five", sourceExcerpt(content, 3, 3))
+	assert.Equal(t, "> 1 | one\n  2 | two\n  3 | three", sourceExcerpt(content, 1, 0))
+	assert.Equal(t, "  4 | four\n  5 | five\n> 6 | six", sourceExcerpt(content, 6, 0))
+	assert.Empty(t, sourceExcerpt(content, 7, 0))
+}
+
+func TestParseError(t *testing.T) {
+	source := newTemplate("test", "page", "<p>\n{{.Title</p>\n</div>")
+	cause := errors.New("template: page:2: unexpected \"<\" in operand")
+
+	err := newParseError(source, cause, false)
+	assert.Equal(t, "page", err.Source)
+	assert.Equal(t, 2, err.Line)
+	assert.Zero(t, err.Column)
+	assert.Empty(t, err.Excerpt)
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.ErrorIs(t, err, cause)
+
+	err = newParseError(source, cause, true)
+	assert.Equal(t, "  1 | <p>\n> 2 | {{.Title</p>\n  3 | </div>", err.Excerpt)
+	assert.Equal(t, cause.Error()+"\n"+err.Excerpt, err.Error())
+
+	err = newParseError(source, errors.New("template: page:1:2: boom"), true)
+	assert.Equal(t, 1, err.Line)
+	assert.Equal(t, 2, err.Column)
+	assert.Contains(t, err.Excerpt, "|   ^")
+}
+
+func TestTheme_Write_ParseErrorExcerpt(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", "<p>\n{{.Title</p>")
+
+	theme := NewTheme("test", store)
+
+	err := theme.Write(context.Background(), io.Discard, "page", nil)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Empty(t, parseErr.Excerpt)
+
+	theme.SetDebug(true)
+
+	err = theme.Write(context.Background(), io.Discard, "page", nil)
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, "page", parseErr.Source)
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Contains(t, err.Error(), "> 2 | {{.Title</p>")
+}
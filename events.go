@@ -0,0 +1,129 @@
+package got
+
+import (
+	"context"
+	"time"
+)
+
+// EventKind is the kind of an Event.
+type EventKind string
+
+const (
+	// EventRenderStart is emitted when a render starts.
+	EventRenderStart EventKind = "render_start"
+	// EventRenderFinish is emitted when a render completes, successfully or
+	// not, along with its duration.
+	EventRenderFinish EventKind = "render_finish"
+	// EventCacheHit is emitted when a compiled template or a page is served
+	// from a cache.
+	EventCacheHit EventKind = "cache_hit"
+	// EventCacheMiss is emitted when a compiled template or a page isn't
+	// cached.
+	EventCacheMiss EventKind = "cache_miss"
+	// EventStoreFetch is emitted when a template is fetched from the stores
+	// while building a template set.
+	EventStoreFetch EventKind = "store_fetch"
+	// EventError is emitted when a render fails.
+	EventError EventKind = "error"
+)
+
+const (
+	// CacheTemplate is the cache of compiled template sets.
+	CacheTemplate = "template"
+	// CachePage is the page cache, see Theme.SetPageCache.
+	CachePage = "page"
+)
+
+// Event describes something that happened while rendering, for analytics
+// or debugging tools.
+type Event struct {
+	Kind     EventKind
+	Time     time.Time
+	Theme    string
+	Template string
+
+	// Duration is the duration of a finished render or store fetch.
+	Duration time.Duration
+
+	// Cache is the cache of a hit or miss, CacheTemplate or CachePage.
+	Cache string
+
+	// Owner is the theme the template of a store fetch was found in.
+	Owner string
+
+	// Err is the error of a failed render or store fetch.
+	Err error
+}
+
+// EventSink receives the events of a theme. Sinks are called synchronously
+// from the rendering goroutine, so they must be fast and safe for
+// concurrent use.
+type EventSink interface {
+	HandleEvent(ctx context.Context, event Event)
+}
+
+// EventSinkFunc is an adapter to use ordinary functions as EventSink.
+type EventSinkFunc func(ctx context.Context, event Event)
+
+func (f EventSinkFunc) HandleEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// EventChannel returns a sink sending the events to ch, for consumers in
+// another goroutine. Events are dropped while ch is full, so rendering is
+// never blocked.
+func EventChannel(ch chan<- Event) EventSink {
+	return EventSinkFunc(func(_ context.Context, event Event) {
+		select {
+		case ch <- event:
+		default:
+		}
+	})
+}
+
+// EventSinks returns the event sinks of the theme.
+func (t *Theme) EventSinks() []EventSink {
+	if sinks := t.sinks.Load(); sinks != nil {
+		return *sinks
+	}
+	return nil
+}
+
+// AddEventSink registers sinks receiving the events of every render of the
+// theme.
+func (t *Theme) AddEventSink(sinks ...EventSink) {
+	for {
+		old := t.sinks.Load()
+
+		var list []EventSink
+		if old != nil {
+			list = append(list, *old...)
+		}
+		list = append(list, sinks...)
+
+		if t.sinks.CompareAndSwap(old, &list) {
+			return
+		}
+	}
+}
+
+// observed reports whether the theme has event sinks, so events are only
+// built when needed.
+func (t *Theme) observed() bool {
+	return t.sinks.Load() != nil
+}
+
+func (t *Theme) emit(ctx context.Context, event Event) {
+	sinks := t.EventSinks()
+	if len(sinks) == 0 {
+		return
+	}
+
+	event.Theme = t.name
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, sink := range sinks {
+		sink.HandleEvent(ctx, event)
+	}
+}
@@ -0,0 +1,125 @@
+package got
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) HandleEvent(_ context.Context, event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) take() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+func eventKinds(events []Event) []EventKind {
+	kinds := make([]EventKind, len(events))
+	for i, event := range events {
+		kinds[i] = event.Kind
+	}
+	return kinds
+}
+
+func TestTheme_Events(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("base", "layouts/base.html", `<main>{{block "content" .}}{{end}}</main>`)
+	store.Add("test", "page", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{.}}{{end}}`)
+	store.Add("test", "broken", `{{index . 5}}`)
+
+	theme := NewTheme("test", store)
+	theme.SetParent(NewTheme("base", store))
+
+	recorder := new(eventRecorder)
+	theme.AddEventSink(recorder)
+	assert.Len(t, theme.EventSinks(), 1)
+
+	require.NoError(t, theme.Write(ctx, io.Discard, "page", "a"))
+
+	events := recorder.take()
+	assert.Equal(t, []EventKind{
+		EventRenderStart,
+		EventCacheMiss,
+		EventStoreFetch,
+		EventStoreFetch,
+		EventStoreFetch,
+		EventRenderFinish,
+	}, eventKinds(events))
+	for _, event := range events {
+		assert.Equal(t, "test", event.Theme)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, CacheTemplate, events[1].Cache)
+	assert.NoError(t, events[5].Err)
+
+	fetches := make(map[string]Event)
+	for _, event := range events[2:5] {
+		fetches[event.Template] = event
+	}
+	assert.Equal(t, "test", fetches["page"].Owner)
+	assert.Equal(t, "base", fetches["layouts/base.html"].Owner)
+	// the block name is looked up as a template too
+	assert.ErrorIs(t, fetches["content"].Err, ErrTemplateNotFound)
+	assert.Empty(t, fetches["content"].Owner)
+
+	require.NoError(t, theme.Write(ctx, io.Discard, "page", "a"))
+	assert.Equal(t, []EventKind{EventRenderStart, EventCacheHit, EventRenderFinish}, eventKinds(recorder.take()))
+
+	require.Error(t, theme.Write(ctx, io.Discard, "broken", nil))
+	events = recorder.take()
+	assert.Equal(t, []EventKind{EventRenderStart, EventCacheMiss, EventStoreFetch, EventRenderFinish, EventError}, eventKinds(events))
+	assert.Error(t, events[3].Err)
+	assert.Equal(t, events[3].Err, events[4].Err)
+
+	theme.SetPageCache(PageCacheOptions{Backend: NewPageCacheMemory(0), TTL: time.Minute})
+
+	_, _, err := theme.RenderPage(ctx, "page", "/", "a")
+	require.NoError(t, err)
+	events = recorder.take()
+	assert.Equal(t, EventCacheMiss, events[0].Kind)
+	assert.Equal(t, CachePage, events[0].Cache)
+
+	_, _, err = theme.RenderPage(ctx, "page", "/", "a")
+	require.NoError(t, err)
+	assert.Equal(t, []Event{{Kind: EventCacheHit, Theme: "test", Template: "page", Cache: CachePage}}, clearTimes(recorder.take()))
+}
+
+func clearTimes(events []Event) []Event {
+	for i := range events {
+		events[i].Time = time.Time{}
+	}
+	return events
+}
+
+func TestEventChannel(t *testing.T) {
+	ch := make(chan Event, 1)
+	sink := EventChannel(ch)
+
+	sink.HandleEvent(context.Background(), Event{Kind: EventRenderStart})
+	sink.HandleEvent(context.Background(), Event{Kind: EventRenderFinish})
+
+	assert.Equal(t, EventRenderStart, (<-ch).Kind)
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event %v", event)
+	default:
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"html"
 	"html/template"
 	"maps"
@@ -17,6 +18,7 @@ import (
 	"github.com/segmentio/go-camelcase"
 	"github.com/segmentio/go-snakecase"
 	"github.com/spf13/cast"
+	"golang.org/x/text/language"
 	"gopkg.in/yaml.v3"
 
 	"github.com/gowool/got/internal"
@@ -126,6 +128,31 @@ var Funcs = template.FuncMap{
 	"str_repeat":      strings.Repeat,
 	"str_len":         func(s string) int { return utf8.RuneCountInString(s) },
 
+	// html functions
+	"attrs":      Attrs,
+	"classnames": ClassNames,
+	"seo":        SEO,
+
+	// navigation functions
+	"menu":        Menu,
+	"breadcrumbs": Breadcrumbs,
+	"paginate":    Paginate,
+	"table":       NewTable,
+
+	// image functions
+	"srcset":  imageFuncs["srcset"],
+	"picture": imageFuncs["picture"],
+
+	// direction functions
+	"locale":       englishBidiFuncs["locale"],
+	"dir":          englishBidiFuncs["dir"],
+	"is_rtl":       englishBidiFuncs["is_rtl"],
+	"start_side":   englishBidiFuncs["start_side"],
+	"end_side":     englishBidiFuncs["end_side"],
+	"dir_choose":   englishBidiFuncs["dir_choose"],
+	"bdi":          englishBidiFuncs["bdi"],
+	"bidi_isolate": englishBidiFuncs["bidi_isolate"],
+
 	// encoding functions
 	"json": func(v any) string {
 		return encode(v, json.Marshal)
@@ -199,31 +226,68 @@ var Funcs = template.FuncMap{
 	"date_utc": func(fmt string, date any) string {
 		return FormatDate(fmt, date, "UTC")
 	},
+	"humanize": func(date any) string {
+		return Humanize(language.English, date, time.Now())
+	},
+
+	// number functions
+	"number_format": func(value any, decimals ...int) string {
+		return FormatNumber(language.English, value, decimals...)
+	},
+}
+
+// StrictFuncs overrides the arithmetic functions of Funcs with variants that
+// fail template execution on division by zero or mismatched operand types,
+// instead of silently yielding nil.
+//
+//	theme.AddFuncMap(got.Funcs)
+//	theme.AddFuncMap(got.StrictFuncs)
+var StrictFuncs = template.FuncMap{
+	"mul": func(inputs ...any) (any, error) {
+		return doArithmeticStrict(inputs, '*')
+	},
+	"div": func(inputs ...any) (any, error) {
+		return doArithmeticStrict(inputs, '/')
+	},
+	"add": func(inputs ...any) (any, error) {
+		return doArithmeticStrict(inputs, '+')
+	},
+	"sub": func(inputs ...any) (any, error) {
+		return doArithmeticStrict(inputs, '-')
+	},
 }
 
 func FormatDate(fmt string, date any, location string) string {
-	var t time.Time
+	return dateIn(date, location).Format(fmt)
+}
+
+// dateIn returns the date in the named location, UTC if it is unknown.
+func dateIn(date any, location string) time.Time {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		loc, _ = time.LoadLocation("UTC")
+	}
+
+	return toTime(date).In(loc)
+}
+
+// toTime converts a time or Unix timestamp, other values give the current
+// time.
+func toTime(date any) time.Time {
 	switch date := date.(type) {
 	case time.Time:
-		t = date
+		return date
 	case *time.Time:
-		t = *date
+		return *date
 	case int64:
-		t = time.Unix(date, 0)
+		return time.Unix(date, 0)
 	case int:
-		t = time.Unix(int64(date), 0)
+		return time.Unix(int64(date), 0)
 	case int32:
-		t = time.Unix(int64(date), 0)
+		return time.Unix(int64(date), 0)
 	default:
-		t = time.Now()
+		return time.Now()
 	}
-
-	loc, err := time.LoadLocation(location)
-	if err != nil {
-		loc, _ = time.LoadLocation("UTC")
-	}
-
-	return t.In(loc).Format(fmt)
 }
 
 func encode(v any, fn func(v any) ([]byte, error)) string {
@@ -242,21 +306,23 @@ func pretty(v any, fn func(v any, prefix, indent string) ([]byte, error)) string
 	return template.JSEscapeString(internal.String(raw))
 }
 
-func doArithmetic(inputs []any, operation rune) (value any) {
-	if len(inputs) < 2 {
-		if len(inputs) == 1 {
-			return inputs[0]
-		}
-		return
+func doArithmetic(inputs []any, operation rune) any {
+	value, _ := doArithmeticStrict(inputs, operation)
+	return value
+}
+
+func doArithmeticStrict(inputs []any, operation rune) (any, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("arithmetic %c: no operands", operation)
 	}
 
-	var err error
-	value = inputs[0]
-	for i := 1; i < len(inputs); i++ {
-		value, err = internal.DoArithmetic(value, inputs[i], operation)
+	value := inputs[0]
+	for _, input := range inputs[1:] {
+		result, err := internal.DoArithmetic(value, input, operation)
 		if err != nil {
-			return
+			return nil, fmt.Errorf("arithmetic %c: %v (%T) and %v (%T): %w", operation, value, value, input, input, err)
 		}
+		value = result
 	}
-	return
+	return value, nil
 }
@@ -0,0 +1,156 @@
+package got
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+var (
+	attrNameRe = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+	urlAttrs   = []string{"action", "background", "cite", "formaction", "href", "icon", "longdesc", "manifest", "poster", "src", "xlink:href"}
+)
+
+// Attrs renders a map as an escaped attribute list, with a leading space,
+// in key order:
+//
+//	<input{{attrs (dict "type" "checkbox" "name" .Name "checked" .Checked)}}>
+//
+// A true value renders a bare attribute, false and nil values are omitted.
+// Event handler attributes and invalid names are dropped, and URLs with an
+// unsafe scheme are replaced like html/template does.
+func Attrs(attrs any) template.HTMLAttr {
+	m := toStringMap(attrs)
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		name := strings.ToLower(key)
+		if !attrNameRe.MatchString(key) || strings.HasPrefix(name, "on") || name == "srcdoc" {
+			continue
+		}
+
+		value := m[key]
+		switch v := value.(type) {
+		case nil:
+			continue
+		case bool:
+			if v {
+				b.WriteByte(' ')
+				b.WriteString(key)
+			}
+			continue
+		}
+
+		s := cast.ToString(value)
+		if slices.Contains(urlAttrs, name) && !isSafeURL(s) {
+			s = "#ZgotmplZ"
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(s))
+		b.WriteByte('"')
+	}
+
+	return template.HTMLAttr(b.String())
+}
+
+// ClassNames joins class names, skipping empty ones. Maps contribute the
+// keys whose value is truthy, slices are flattened:
+//
+//	<a class="{{classnames "btn" (dict "active" .IsActive "disabled" .IsDisabled)}}">
+func ClassNames(args ...any) string {
+	var names []string
+
+	var add func(arg any)
+	add = func(arg any) {
+		switch v := arg.(type) {
+		case nil:
+		case string:
+			names = append(names, strings.Fields(v)...)
+		case []string:
+			for _, item := range v {
+				add(item)
+			}
+		case []any:
+			for _, item := range v {
+				add(item)
+			}
+		default:
+			rv := reflect.ValueOf(arg)
+			if rv.Kind() != reflect.Map {
+				names = append(names, strings.Fields(fmt.Sprint(arg))...)
+				return
+			}
+
+			m := toStringMap(arg)
+			keys := make([]string, 0, len(m))
+			for key, value := range m {
+				if truth, _ := template.IsTrue(value); truth {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+			for _, key := range keys {
+				add(key)
+			}
+		}
+	}
+
+	for _, arg := range args {
+		add(arg)
+	}
+
+	seen := make(map[string]struct{}, len(names))
+	names = slices.DeleteFunc(names, func(name string) bool {
+		if _, ok := seen[name]; ok {
+			return true
+		}
+		seen[name] = struct{}{}
+		return false
+	})
+
+	return strings.Join(names, " ")
+}
+
+func toStringMap(m any) map[string]any {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map {
+		return nil
+	}
+
+	result := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		result[cast.ToString(iter.Key().Interface())] = iter.Value().Interface()
+	}
+	return result
+}
+
+// isSafeURL reports whether the URL is relative or uses a scheme allowed by
+// html/template.
+func isSafeURL(s string) bool {
+	scheme, _, ok := strings.Cut(s, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true
+	}
+
+	switch strings.ToLower(strings.TrimSpace(scheme)) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package got
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttrs(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs any
+		want  template.HTMLAttr
+	}{
+		{
+			name:  "nil",
+			attrs: nil,
+			want:  "",
+		},
+		{
+			name:  "sorted and escaped",
+			attrs: map[any]any{"type": "text", "value": `"><script>`, "data-id": 5},
+			want:  ` data-id="5" type="text" value="&#34;&gt;&lt;script&gt;"`,
+		},
+		{
+			name:  "boolean attributes",
+			attrs: map[string]any{"checked": true, "disabled": false, "hidden": nil},
+			want:  ` checked`,
+		},
+		{
+			name:  "drops event handlers and invalid names",
+			attrs: map[string]any{"onclick": "alert(1)", `a"b`: "x", "srcdoc": "<p>", "id": "x"},
+			want:  ` id="x"`,
+		},
+		{
+			name:  "filters unsafe urls",
+			attrs: map[string]any{"href": "javascript:alert(1)", "src": "/img.png"},
+			want:  ` href="#ZgotmplZ" src="/img.png"`,
+		},
+		{
+			name:  "allows safe urls",
+			attrs: map[string]string{"href": "https://example.com/?a=1&b=2"},
+			want:  ` href="https://example.com/?a=1&amp;b=2"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Attrs(tt.attrs))
+		})
+	}
+}
+
+func TestClassNames(t *testing.T) {
+	tests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{
+			name: "empty",
+			want: "",
+		},
+		{
+			name: "strings",
+			args: []any{"btn", "", " btn-lg  primary "},
+			want: "btn btn-lg primary",
+		},
+		{
+			name: "conditional",
+			args: []any{"btn", map[any]any{"disabled": false, "active": true, "open": 1, "empty": ""}},
+			want: "btn active open",
+		},
+		{
+			name: "slices and duplicates",
+			args: []any{[]string{"a", "b"}, []any{"b", nil, "c"}, "a"},
+			want: "a b c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassNames(tt.args...))
+		})
+	}
+}
+
+func TestAttrs_InTemplate(t *testing.T) {
+	tpl, err := template.New("test").Funcs(Funcs).Parse(
+		`<a{{attrs (dict "href" .URL "title" .Title)}} class="{{classnames "link" (dict "active" .Active)}}">x</a>`,
+	)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	err = tpl.Execute(&b, map[string]any{"URL": "/a?b=1&c=2", "Title": `"quoted"`, "Active": true})
+	require.NoError(t, err)
+	assert.Equal(t, `<a href="/a?b=1&amp;c=2" title="&#34;quoted&#34;" class="link active">x</a>`, b.String())
+}
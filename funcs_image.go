@@ -0,0 +1,164 @@
+package got
+
+import (
+	"html/template"
+	"mime"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// ImageOptions describes a variant of an image requested from an
+// ImageTransformer. Zero values keep the original.
+type ImageOptions struct {
+	Width  int
+	Format string
+}
+
+// ImageTransformer returns the URL of a variant of an image, typically by
+// encoding the options the way an image CDN expects them.
+type ImageTransformer interface {
+	Transform(src string, options ImageOptions) string
+}
+
+type ImageTransformerFunc func(src string, options ImageOptions) string
+
+func (f ImageTransformerFunc) Transform(src string, options ImageOptions) string {
+	return f(src, options)
+}
+
+// QueryImageTransformer sets the width and format as query parameters of
+// the image URL, omitting a parameter when its name is empty.
+func QueryImageTransformer(width, format string) ImageTransformer {
+	return ImageTransformerFunc(func(src string, options ImageOptions) string {
+		u, err := url.Parse(src)
+		if err != nil {
+			return src
+		}
+
+		query := u.Query()
+		if width != "" && options.Width > 0 {
+			query.Set(width, strconv.Itoa(options.Width))
+		}
+		if format != "" && options.Format != "" {
+			query.Set(format, options.Format)
+		}
+		u.RawQuery = query.Encode()
+
+		return u.String()
+	})
+}
+
+// ImgixTransformer encodes image variants the way imgix and compatible
+// CDNs expect them.
+var ImgixTransformer = QueryImageTransformer("w", "fm")
+
+var imageFuncs = ImageFuncs(ImgixTransformer)
+
+// ImageFuncs returns the responsive image functions using the transformer:
+//
+//	<img src="/a.jpg" srcset="{{srcset "/a.jpg" 320 640 1280}}" sizes="100vw">
+//	{{picture "/a.jpg" (list 320 640) (dict "formats" (list "avif" "webp") "sizes" "100vw" "alt" .Alt)}}
+//
+// Funcs uses ImgixTransformer, themes served through another CDN override
+// them with theme.AddFuncMap(got.ImageFuncs(transformer)).
+func ImageFuncs(transformer ImageTransformer) template.FuncMap {
+	return template.FuncMap{
+		"srcset": func(src string, widths ...any) template.Srcset {
+			return srcset(transformer, src, "", toWidths(widths))
+		},
+		"picture": func(src string, widths any, options ...any) template.HTML {
+			var opts map[string]any
+			if len(options) > 0 {
+				opts = toStringMap(options[0])
+			}
+			return picture(transformer, src, toWidths(cast.ToSlice(widths)), opts)
+		},
+	}
+}
+
+func toWidths(values []any) []int {
+	widths := make([]int, 0, len(values))
+	for _, value := range values {
+		if width := cast.ToInt(value); width > 0 {
+			widths = append(widths, width)
+		}
+	}
+	slices.Sort(widths)
+	return slices.Compact(widths)
+}
+
+func srcset(transformer ImageTransformer, src, format string, widths []int) template.Srcset {
+	candidates := make([]string, 0, len(widths))
+	for _, width := range widths {
+		u := transformer.Transform(src, ImageOptions{Width: width, Format: format})
+		candidates = append(candidates, srcsetURL(u)+" "+strconv.Itoa(width)+"w")
+	}
+	return template.Srcset(strings.Join(candidates, ", "))
+}
+
+// srcsetURL makes the URL safe to embed into a srcset, where commas and
+// spaces separate candidates.
+func srcsetURL(u string) string {
+	if !isSafeURL(u) {
+		return "#ZgotmplZ"
+	}
+	return strings.NewReplacer(",", "%2C", " ", "%20").Replace(u)
+}
+
+func imageType(format string) string {
+	if typ := mime.TypeByExtension("." + format); typ != "" {
+		return typ
+	}
+	return "image/" + format
+}
+
+// picture renders a <picture> element with a <source> per format in the
+// "formats" option and an <img> fallback. The "sizes" option applies to
+// every source, the remaining options become attributes of the <img>.
+func picture(transformer ImageTransformer, src string, widths []int, options map[string]any) template.HTML {
+	var sizes any
+	if s := cast.ToString(options["sizes"]); s != "" {
+		sizes = s
+	}
+	formats := cast.ToStringSlice(options["formats"])
+
+	img := make(map[string]any, len(options)+3)
+	for key, value := range options {
+		if key != "sizes" && key != "formats" {
+			img[key] = value
+		}
+	}
+	if _, ok := img["loading"]; !ok {
+		img["loading"] = "lazy"
+	}
+
+	var b strings.Builder
+	b.WriteString("<picture>")
+	for _, format := range formats {
+		b.WriteString("<source")
+		b.WriteString(string(Attrs(map[string]any{
+			"type":   imageType(format),
+			"srcset": string(srcset(transformer, src, format, widths)),
+			"sizes":  sizes,
+		})))
+		b.WriteString(">")
+	}
+
+	fallback := transformer.Transform(src, ImageOptions{})
+	if len(widths) > 0 {
+		fallback = transformer.Transform(src, ImageOptions{Width: widths[len(widths)-1]})
+		img["srcset"] = string(srcset(transformer, src, "", widths))
+		img["sizes"] = sizes
+	}
+	img["src"] = fallback
+
+	b.WriteString("<img")
+	b.WriteString(string(Attrs(img)))
+	b.WriteString("></picture>")
+
+	return template.HTML(b.String())
+}
@@ -0,0 +1,86 @@
+package got
+
+import (
+	"html/template"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryImageTransformer(t *testing.T) {
+	tests := []struct {
+		name        string
+		transformer ImageTransformer
+		src         string
+		options     ImageOptions
+		want        string
+	}{
+		{
+			name:        "width and format",
+			transformer: ImgixTransformer,
+			src:         "https://cdn.example.com/a.jpg?q=80",
+			options:     ImageOptions{Width: 320, Format: "webp"},
+			want:        "https://cdn.example.com/a.jpg?fm=webp&q=80&w=320",
+		},
+		{
+			name:        "original",
+			transformer: ImgixTransformer,
+			src:         "/a.jpg",
+			want:        "/a.jpg",
+		},
+		{
+			name:        "custom parameters",
+			transformer: QueryImageTransformer("width", ""),
+			src:         "/a.jpg",
+			options:     ImageOptions{Width: 640, Format: "avif"},
+			want:        "/a.jpg?width=640",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.transformer.Transform(tt.src, tt.options))
+		})
+	}
+}
+
+func TestImageFuncs_Srcset(t *testing.T) {
+	funcs := ImageFuncs(ImageTransformerFunc(func(src string, options ImageOptions) string {
+		return strings.Replace(src, "/img/", "/img/w_"+strconv.Itoa(options.Width)+",f_auto/", 1)
+	}))
+	srcset := funcs["srcset"].(func(string, ...any) template.Srcset)
+
+	assert.Equal(t, template.Srcset("/img/w_320%2Cf_auto/a.jpg 320w, /img/w_640%2Cf_auto/a.jpg 640w"), srcset("/img/a.jpg", 640, "320", 0, 640))
+	assert.Equal(t, template.Srcset("#ZgotmplZ 320w"), srcset("javascript:alert(1)//img/a.jpg", 320))
+	assert.Equal(t, template.Srcset(""), srcset("/img/a.jpg"))
+}
+
+func TestImageFuncs_Picture(t *testing.T) {
+	picture := ImageFuncs(ImgixTransformer)["picture"].(func(string, any, ...any) template.HTML)
+
+	got := picture("/a.jpg", []any{640, 320}, map[any]any{
+		"formats": []any{"avif", "webp"},
+		"sizes":   "100vw",
+		"alt":     `A "cat"`,
+	})
+	assert.Equal(t, template.HTML(`<picture>`+
+		`<source sizes="100vw" srcset="/a.jpg?fm=avif&amp;w=320 320w, /a.jpg?fm=avif&amp;w=640 640w" type="image/avif">`+
+		`<source sizes="100vw" srcset="/a.jpg?fm=webp&amp;w=320 320w, /a.jpg?fm=webp&amp;w=640 640w" type="image/webp">`+
+		`<img alt="A &#34;cat&#34;" loading="lazy" sizes="100vw" src="/a.jpg?w=640" srcset="/a.jpg?w=320 320w, /a.jpg?w=640 640w">`+
+		`</picture>`), got)
+
+	got = picture("/a.jpg", nil)
+	assert.Equal(t, template.HTML(`<picture><img loading="lazy" src="/a.jpg"></picture>`), got)
+}
+
+func TestImageFuncs_InTemplate(t *testing.T) {
+	tpl, err := template.New("test").Funcs(Funcs).Parse(`<img src="/a.jpg" srcset="{{srcset "/a.jpg" 320 640}}">`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, tpl.Execute(&b, nil))
+	assert.Equal(t, `<img src="/a.jpg" srcset="/a.jpg?w=320 320w, /a.jpg?w=640 640w">`, b.String())
+}
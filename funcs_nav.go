@@ -0,0 +1,181 @@
+package got
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// MenuItem is a navigation entry. Active marks the entry matching the
+// current path, Ancestor marks entries leading to it.
+type MenuItem struct {
+	Title    string
+	URL      string
+	Children []MenuItem
+	Meta     map[string]any
+	Active   bool
+	Ancestor bool
+}
+
+// Menu returns a copy of the menu with Active and Ancestor set for the
+// current path. Items may be a []MenuItem or a list of maps with "title",
+// "url", "children" and "meta" keys. Besides nesting, an entry is an
+// ancestor when its URL is a path prefix of current, so flat menus work too.
+func Menu(items any, current string) ([]MenuItem, error) {
+	menu, err := toMenuItems(items)
+	if err != nil {
+		return nil, err
+	}
+	markMenu(menu, cleanNavPath(current))
+	return menu, nil
+}
+
+// Breadcrumbs returns the trail of entries leading to the current path,
+// from the outermost ancestor to the active entry.
+func Breadcrumbs(items any, current string) ([]MenuItem, error) {
+	menu, err := Menu(items, current)
+	if err != nil {
+		return nil, err
+	}
+
+	type crumb struct {
+		item  MenuItem
+		depth int
+	}
+	var trail []crumb
+
+	var walk func(items []MenuItem, depth int)
+	walk = func(items []MenuItem, depth int) {
+		for _, item := range items {
+			if !item.Active && !item.Ancestor {
+				continue
+			}
+			children := item.Children
+			item.Children = nil
+			trail = append(trail, crumb{item: item, depth: depth})
+			walk(children, depth+1)
+		}
+	}
+	walk(menu, 0)
+
+	slices.SortStableFunc(trail, func(a, b crumb) int {
+		if a.depth != b.depth {
+			return a.depth - b.depth
+		}
+		return len(cleanNavPath(a.item.URL)) - len(cleanNavPath(b.item.URL))
+	})
+
+	result := make([]MenuItem, 0, len(trail))
+	for _, c := range trail {
+		if !slices.ContainsFunc(result, func(item MenuItem) bool { return item.URL == c.item.URL }) {
+			result = append(result, c.item)
+		}
+	}
+	return result, nil
+}
+
+func markMenu(items []MenuItem, current string) (found bool) {
+	for i := range items {
+		item := &items[i]
+		url := cleanNavPath(item.URL)
+
+		item.Active = url != "" && url == current
+		item.Ancestor = markMenu(item.Children, current) ||
+			(!item.Active && url != "" && url != "/" && strings.HasPrefix(current, url+"/"))
+
+		found = found || item.Active || item.Ancestor
+	}
+	return found
+}
+
+func cleanNavPath(p string) string {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+func toMenuItems(items any) ([]MenuItem, error) {
+	switch v := items.(type) {
+	case nil:
+		return nil, nil
+	case []MenuItem:
+		menu := make([]MenuItem, len(v))
+		for i, item := range v {
+			children, err := toMenuItems(item.Children)
+			if err != nil {
+				return nil, err
+			}
+			item.Children = children
+			menu[i] = item
+		}
+		return menu, nil
+	case []*MenuItem:
+		menu := make([]MenuItem, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				menu = append(menu, *item)
+			}
+		}
+		return toMenuItems(menu)
+	case []map[string]any:
+		menu := make([]any, len(v))
+		for i, item := range v {
+			menu[i] = item
+		}
+		return toMenuItems(menu)
+	case []any:
+		menu := make([]MenuItem, 0, len(v))
+		for _, item := range v {
+			m, err := toMenuItem(item)
+			if err != nil {
+				return nil, err
+			}
+			menu = append(menu, m)
+		}
+		return menu, nil
+	}
+	return nil, fmt.Errorf("menu: unsupported items type %T", items)
+}
+
+func toMenuItem(item any) (MenuItem, error) {
+	switch v := item.(type) {
+	case MenuItem:
+		children, err := toMenuItems(v.Children)
+		v.Children = children
+		return v, err
+	case *MenuItem:
+		if v != nil {
+			return toMenuItem(*v)
+		}
+	}
+
+	m := toStringMap(item)
+	if m == nil {
+		return MenuItem{}, fmt.Errorf("menu: unsupported item type %T", item)
+	}
+
+	var menuItem MenuItem
+	for key, value := range m {
+		switch strings.ToLower(key) {
+		case "title":
+			menuItem.Title = cast.ToString(value)
+		case "url":
+			menuItem.URL = cast.ToString(value)
+		case "meta":
+			menuItem.Meta = toStringMap(value)
+		case "children":
+			children, err := toMenuItems(value)
+			if err != nil {
+				return MenuItem{}, err
+			}
+			menuItem.Children = children
+		}
+	}
+	return menuItem, nil
+}
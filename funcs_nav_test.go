@@ -0,0 +1,116 @@
+package got
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMenu() []MenuItem {
+	return []MenuItem{
+		{Title: "Home", URL: "/"},
+		{Title: "Docs", URL: "/docs", Children: []MenuItem{
+			{Title: "Install", URL: "/docs/install"},
+			{Title: "Guide", URL: "/docs/guide/", Children: []MenuItem{
+				{Title: "Themes", URL: "/docs/guide/themes"},
+			}},
+		}},
+		{Title: "Blog", URL: "/blog"},
+	}
+}
+
+func TestMenu(t *testing.T) {
+	items := testMenu()
+
+	menu, err := Menu(items, "/docs/guide/themes?x=1")
+	require.NoError(t, err)
+
+	assert.False(t, menu[0].Active || menu[0].Ancestor)
+	assert.True(t, menu[1].Ancestor)
+	assert.False(t, menu[1].Active)
+	assert.False(t, menu[1].Children[0].Active || menu[1].Children[0].Ancestor)
+	assert.True(t, menu[1].Children[1].Ancestor)
+	assert.True(t, menu[1].Children[1].Children[0].Active)
+	assert.False(t, menu[2].Active || menu[2].Ancestor)
+
+	assert.False(t, items[1].Children[1].Children[0].Active, "input must not be modified")
+}
+
+func TestMenu_Flat(t *testing.T) {
+	menu, err := Menu([]MenuItem{{URL: "/"}, {URL: "/blog"}, {URL: "/about"}}, "/blog/2024/post/")
+	require.NoError(t, err)
+
+	assert.False(t, menu[0].Ancestor, "root is not an ancestor by prefix")
+	assert.True(t, menu[1].Ancestor)
+	assert.False(t, menu[2].Ancestor)
+
+	menu, err = Menu([]MenuItem{{URL: "/"}, {URL: "/blog"}}, "/")
+	require.NoError(t, err)
+	assert.True(t, menu[0].Active)
+	assert.False(t, menu[1].Active)
+}
+
+func TestMenu_Maps(t *testing.T) {
+	items := []any{
+		map[string]any{"title": "Home", "url": "/"},
+		map[any]any{"Title": "Docs", "URL": "/docs", "meta": map[string]any{"icon": "book"}, "children": []any{
+			map[string]any{"title": "Install", "url": "/docs/install"},
+		}},
+	}
+
+	menu, err := Menu(items, "/docs/install")
+	require.NoError(t, err)
+	require.Len(t, menu, 2)
+	assert.Equal(t, "Docs", menu[1].Title)
+	assert.Equal(t, map[string]any{"icon": "book"}, menu[1].Meta)
+	assert.True(t, menu[1].Ancestor)
+	assert.True(t, menu[1].Children[0].Active)
+
+	_, err = Menu("invalid", "/")
+	assert.Error(t, err)
+
+	_, err = Menu([]any{1}, "/")
+	assert.Error(t, err)
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	titles := func(items []MenuItem) []string {
+		var result []string
+		for _, item := range items {
+			assert.Nil(t, item.Children)
+			result = append(result, item.Title)
+		}
+		return result
+	}
+
+	crumbs, err := Breadcrumbs(testMenu(), "/docs/guide/themes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Docs", "Guide", "Themes"}, titles(crumbs))
+	assert.True(t, crumbs[2].Active)
+
+	crumbs, err = Breadcrumbs([]MenuItem{
+		{Title: "Post", URL: "/blog/2024/post"},
+		{Title: "2024", URL: "/blog/2024"},
+		{Title: "Blog", URL: "/blog"},
+	}, "/blog/2024/post")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Blog", "2024", "Post"}, titles(crumbs))
+
+	crumbs, err = Breadcrumbs(testMenu(), "/missing")
+	require.NoError(t, err)
+	assert.Empty(t, crumbs)
+}
+
+func TestMenu_InTemplate(t *testing.T) {
+	tpl, err := template.New("test").Funcs(Funcs).Parse(
+		`{{range menu .Menu .Path}}<a{{if .Active}} aria-current="page"{{end}}{{if .Ancestor}} class="open"{{end}}>{{.Title}}</a>{{end}}`,
+	)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, tpl.Execute(&b, map[string]any{"Menu": testMenu(), "Path": "/docs/install"}))
+	assert.Equal(t, `<a>Home</a><a class="open">Docs</a><a>Blog</a>`, b.String())
+}
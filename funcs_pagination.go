@@ -0,0 +1,108 @@
+package got
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cast"
+)
+
+// Pagination describes a page of a paginated list. It is rendered by the
+// built-in "components/pagination.html" template:
+//
+//	{{template "components/pagination.html" (paginate .Total 20 .Page "/posts")}}
+type Pagination struct {
+	Total   int
+	PerPage int
+	Page    int
+	Pages   int
+	// URL is the address of the list, the page number is set in its
+	// "page" query parameter.
+	URL string
+}
+
+// Paginate returns the pagination of total items split into pages of
+// perPage items, clamping page into the valid range.
+func Paginate(total, perPage, page any, url ...string) Pagination {
+	p := Pagination{
+		Total:   max(cast.ToInt(total), 0),
+		PerPage: max(cast.ToInt(perPage), 1),
+		Page:    cast.ToInt(page),
+	}
+	if len(url) > 0 {
+		p.URL = url[0]
+	}
+
+	p.Pages = p.Total / p.PerPage
+	if p.Total%p.PerPage != 0 {
+		p.Pages++
+	}
+	p.Pages = max(p.Pages, 1)
+	p.Page = min(max(p.Page, 1), p.Pages)
+
+	return p
+}
+
+// Offset returns the index of the first item of the page.
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+func (p Pagination) HasPrev() bool {
+	return p.Page > 1
+}
+
+func (p Pagination) HasNext() bool {
+	return p.Page < p.Pages
+}
+
+func (p Pagination) Prev() int {
+	return max(p.Page-1, 1)
+}
+
+func (p Pagination) Next() int {
+	return min(p.Page+1, p.Pages)
+}
+
+// Range returns the page numbers to link to: the first and last pages and
+// window pages around the current one. Gaps are marked with 0.
+func (p Pagination) Range(window int) []int {
+	window = min(max(window, 0), p.Pages)
+
+	pages := []int{1}
+	if p.Pages == 1 {
+		return pages
+	}
+
+	lo := max(p.Page-window, 2)
+	hi := min(p.Page+window, p.Pages-1)
+	if lo > 2 {
+		pages = append(pages, 0)
+	}
+	for i := lo; i <= hi; i++ {
+		pages = append(pages, i)
+	}
+	if hi < p.Pages-1 {
+		pages = append(pages, 0)
+	}
+	return append(pages, p.Pages)
+}
+
+// PageURL returns URL with the page query parameter set, omitting it for the
+// first page.
+func (p Pagination) PageURL(page int) string {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return p.URL
+	}
+
+	query := u.Query()
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page))
+	} else {
+		query.Del("page")
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
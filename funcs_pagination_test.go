@@ -0,0 +1,90 @@
+package got
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   any
+		perPage any
+		page    any
+		want    Pagination
+	}{
+		{
+			name:    "middle page",
+			total:   95,
+			perPage: 10,
+			page:    "3",
+			want:    Pagination{Total: 95, PerPage: 10, Page: 3, Pages: 10},
+		},
+		{
+			name:    "page out of range",
+			total:   20,
+			perPage: 10,
+			page:    7,
+			want:    Pagination{Total: 20, PerPage: 10, Page: 2, Pages: 2},
+		},
+		{
+			name:    "total near the int limit",
+			total:   math.MaxInt,
+			perPage: 2,
+			page:    1,
+			want:    Pagination{Total: math.MaxInt, PerPage: 2, Page: 1, Pages: math.MaxInt/2 + 1},
+		},
+		{
+			name:    "empty list",
+			total:   0,
+			perPage: 0,
+			page:    0,
+			want:    Pagination{Total: 0, PerPage: 1, Page: 1, Pages: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Paginate(tt.total, tt.perPage, tt.page))
+		})
+	}
+}
+
+func TestPagination_Navigation(t *testing.T) {
+	p := Paginate(95, 10, 3)
+	assert.Equal(t, 20, p.Offset())
+	assert.True(t, p.HasPrev())
+	assert.True(t, p.HasNext())
+	assert.Equal(t, 2, p.Prev())
+	assert.Equal(t, 4, p.Next())
+
+	p = Paginate(5, 10, 1)
+	assert.False(t, p.HasPrev())
+	assert.False(t, p.HasNext())
+	assert.Equal(t, 1, p.Prev())
+	assert.Equal(t, 1, p.Next())
+}
+
+func TestPagination_Range(t *testing.T) {
+	assert.Equal(t, []int{1, 0, 4, 5, 6, 0, 10}, Paginate(100, 10, 5).Range(1))
+	assert.Equal(t, []int{1, 2, 3, 0, 10}, Paginate(100, 10, 1).Range(2))
+	assert.Equal(t, []int{1, 0, 9, 10}, Paginate(100, 10, 10).Range(1))
+	assert.Equal(t, []int{1, 2, 3}, Paginate(30, 10, 2).Range(0))
+	assert.Equal(t, []int{1}, Paginate(0, 10, 1).Range(-1))
+	assert.Equal(t, []int{1, 2, 3, 4}, Paginate(40, 10, 2).Range(math.MaxInt))
+
+	huge := Paginate(math.MaxInt, 1, math.MaxInt/2)
+	assert.Equal(t, []int{1, 0, math.MaxInt/2 - 1, math.MaxInt / 2, math.MaxInt/2 + 1, 0, math.MaxInt}, huge.Range(1))
+}
+
+func TestPagination_PageURL(t *testing.T) {
+	p := Paginate(100, 10, 1, "/posts?tag=go&page=4")
+	assert.Equal(t, "/posts?page=2&tag=go", p.PageURL(2))
+	assert.Equal(t, "/posts?tag=go", p.PageURL(1))
+
+	p = Paginate(100, 10, 1)
+	assert.Equal(t, "?page=3", p.PageURL(3))
+	assert.Equal(t, "", p.PageURL(1))
+}
@@ -0,0 +1,131 @@
+package got
+
+import (
+	"html"
+	"html/template"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+type seoTag struct {
+	attr, key, value string
+}
+
+// SEO renders the title, meta description, canonical link, Open Graph and
+// Twitter card tags of a page:
+//
+//	<head>{{seo (dict "title" .Title "description" .Summary "image" .Cover "url" .URL)}}</head>
+//
+// Recognized keys are title, description, keywords, robots, canonical, url,
+// image, type, site_name, locale, twitter_site and twitter_creator. The
+// Open Graph and Twitter tags fall back to them, while keys containing a
+// colon, such as "og:type" or "article:author", are rendered as given and
+// override the fallbacks. Empty values are omitted.
+func SEO(values any) template.HTML {
+	m := toStringMap(values)
+	get := func(key string) string {
+		return strings.TrimSpace(cast.ToString(m[key]))
+	}
+
+	title := get("title")
+	description := get("description")
+	image := get("image")
+	url := get("url")
+
+	canonical := get("canonical")
+	if canonical == "" {
+		canonical = url
+	}
+
+	keywords := get("keywords")
+	if list, ok := m["keywords"].([]any); ok {
+		keywords = strings.Join(cast.ToStringSlice(list), ", ")
+	} else if list, ok := m["keywords"].([]string); ok {
+		keywords = strings.Join(list, ", ")
+	}
+
+	ogType := get("type")
+	if ogType == "" {
+		ogType = "website"
+	}
+
+	card := "summary"
+	if image != "" {
+		card = "summary_large_image"
+	}
+
+	tags := []seoTag{
+		{"name", "description", description},
+		{"name", "keywords", keywords},
+		{"name", "robots", get("robots")},
+		{"property", "og:title", title},
+		{"property", "og:description", description},
+		{"property", "og:type", ogType},
+		{"property", "og:url", url},
+		{"property", "og:image", image},
+		{"property", "og:site_name", get("site_name")},
+		{"property", "og:locale", get("locale")},
+		{"name", "twitter:card", card},
+		{"name", "twitter:title", title},
+		{"name", "twitter:description", description},
+		{"name", "twitter:image", image},
+		{"name", "twitter:site", get("twitter_site")},
+		{"name", "twitter:creator", get("twitter_creator")},
+	}
+
+	var custom []string
+	for key := range m {
+		if strings.Contains(key, ":") {
+			custom = append(custom, key)
+		}
+	}
+	slices.Sort(custom)
+
+	for _, key := range custom {
+		i := slices.IndexFunc(tags, func(tag seoTag) bool { return tag.key == key })
+		if i >= 0 {
+			tags[i].value = get(key)
+			continue
+		}
+
+		attr := "property"
+		if strings.HasPrefix(key, "twitter:") {
+			attr = "name"
+		}
+		tags = append(tags, seoTag{attr, key, get(key)})
+	}
+
+	var b strings.Builder
+	if title != "" {
+		b.WriteString("<title>")
+		b.WriteString(html.EscapeString(title))
+		b.WriteString("</title>\n")
+	}
+	if canonical != "" && isSafeURL(canonical) {
+		b.WriteString(`<link rel="canonical" href="`)
+		b.WriteString(html.EscapeString(canonical))
+		b.WriteString("\">\n")
+	}
+	for _, tag := range tags {
+		if tag.value == "" {
+			continue
+		}
+		if strings.HasSuffix(tag.key, ":url") || strings.HasSuffix(tag.key, ":image") {
+			if !isSafeURL(tag.value) {
+				continue
+			}
+		}
+
+		b.WriteString(`<meta `)
+		b.WriteString(tag.attr)
+		b.WriteString(`="`)
+		b.WriteString(html.EscapeString(tag.key))
+		b.WriteString(`" content="`)
+		b.WriteString(html.EscapeString(tag.value))
+		b.WriteString("\">\n")
+	}
+
+	return template.HTML(b.String())
+}
@@ -0,0 +1,98 @@
+package got
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSEO(t *testing.T) {
+	tests := []struct {
+		name   string
+		values any
+		want   string
+	}{
+		{
+			name:   "empty",
+			values: nil,
+			want: `<meta property="og:type" content="website">
+<meta name="twitter:card" content="summary">
+`,
+		},
+		{
+			name: "fallbacks",
+			values: map[any]any{
+				"title":       `Cats & "Dogs"`,
+				"description": "About pets",
+				"url":         "https://example.com/pets",
+				"image":       "https://example.com/pets.jpg",
+				"keywords":    []any{"cats", "dogs"},
+			},
+			want: `<title>Cats &amp; &#34;Dogs&#34;</title>
+<link rel="canonical" href="https://example.com/pets">
+<meta name="description" content="About pets">
+<meta name="keywords" content="cats, dogs">
+<meta property="og:title" content="Cats &amp; &#34;Dogs&#34;">
+<meta property="og:description" content="About pets">
+<meta property="og:type" content="website">
+<meta property="og:url" content="https://example.com/pets">
+<meta property="og:image" content="https://example.com/pets.jpg">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:title" content="Cats &amp; &#34;Dogs&#34;">
+<meta name="twitter:description" content="About pets">
+<meta name="twitter:image" content="https://example.com/pets.jpg">
+`,
+		},
+		{
+			name: "overrides and custom tags",
+			values: map[string]any{
+				"title":          "Post",
+				"canonical":      "/post",
+				"og:title":       "Shared post",
+				"type":           "article",
+				"article:author": "Ann",
+				"twitter:label1": "Reading time",
+				"twitter_site":   "@example",
+			},
+			want: `<title>Post</title>
+<link rel="canonical" href="/post">
+<meta property="og:title" content="Shared post">
+<meta property="og:type" content="article">
+<meta name="twitter:card" content="summary">
+<meta name="twitter:title" content="Post">
+<meta name="twitter:site" content="@example">
+<meta property="article:author" content="Ann">
+<meta name="twitter:label1" content="Reading time">
+`,
+		},
+		{
+			name: "unsafe urls",
+			values: map[string]any{
+				"url":   "javascript:alert(1)",
+				"image": "javascript:alert(2)",
+			},
+			want: `<meta property="og:type" content="website">
+<meta name="twitter:card" content="summary_large_image">
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, template.HTML(tt.want), SEO(tt.values))
+		})
+	}
+}
+
+func TestSEO_InTemplate(t *testing.T) {
+	tpl, err := template.New("test").Funcs(Funcs).Parse(`<head>{{seo (dict "title" .Title)}}</head>`)
+	require.NoError(t, err)
+
+	var b strings.Builder
+	require.NoError(t, tpl.Execute(&b, map[string]any{"Title": "<Home>"}))
+	assert.Contains(t, b.String(), `<title>&lt;Home&gt;</title>`)
+	assert.Contains(t, b.String(), `<meta property="og:title" content="&lt;Home&gt;">`)
+}
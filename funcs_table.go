@@ -0,0 +1,175 @@
+package got
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// TableColumn describes a column of a Table. Key selects the value of each
+// row: a map key or a struct field, with dots descending into nested
+// values. Format, when set, converts the value into the rendered cell.
+type TableColumn struct {
+	Key    string
+	Label  string
+	Format func(value any) any
+}
+
+// Table is a slice of rows split into cells, rendered by the built-in
+// "components/table.html" template:
+//
+//	{{template "components/table.html" (table .Users "Name" "Email:E-mail")}}
+type Table struct {
+	Columns []TableColumn
+	Rows    [][]any
+}
+
+// NewTable builds a table from a slice of structs or maps. Columns may be
+// TableColumn values, "key" or "key:Label" strings, or maps with "key",
+// "label" and "format" entries. Without columns every exported struct field
+// or every map key of the first row becomes a column.
+func NewTable(rows any, columns ...any) (Table, error) {
+	var table Table
+	for _, column := range columns {
+		c, err := toTableColumn(column)
+		if err != nil {
+			return Table{}, err
+		}
+		table.Columns = append(table.Columns, c)
+	}
+
+	rv := indirect(reflect.ValueOf(rows))
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return table, nil
+	case reflect.Slice, reflect.Array:
+	default:
+		return Table{}, fmt.Errorf("table: unsupported rows type %T", rows)
+	}
+
+	if len(table.Columns) == 0 && rv.Len() > 0 {
+		for _, key := range tableKeys(rv.Index(0)) {
+			table.Columns = append(table.Columns, TableColumn{Key: key, Label: key})
+		}
+	}
+
+	table.Rows = make([][]any, rv.Len())
+	for i := range table.Rows {
+		row := rv.Index(i)
+		cells := make([]any, len(table.Columns))
+		for j, column := range table.Columns {
+			value := tableValue(row, column.Key)
+			if column.Format != nil {
+				value = column.Format(value)
+			}
+			cells[j] = value
+		}
+		table.Rows[i] = cells
+	}
+
+	return table, nil
+}
+
+func toTableColumn(column any) (TableColumn, error) {
+	switch v := column.(type) {
+	case TableColumn:
+		if v.Label == "" {
+			v.Label = v.Key
+		}
+		return v, nil
+	case string:
+		key, label, ok := strings.Cut(v, ":")
+		if !ok {
+			label = key
+		}
+		return TableColumn{Key: key, Label: label}, nil
+	}
+
+	m := toStringMap(column)
+	if m == nil {
+		return TableColumn{}, fmt.Errorf("table: unsupported column type %T", column)
+	}
+
+	c := TableColumn{Key: cast.ToString(m["key"]), Label: cast.ToString(m["label"])}
+	if c.Label == "" {
+		c.Label = c.Key
+	}
+
+	switch format := m["format"].(type) {
+	case nil:
+	case func(any) any:
+		c.Format = format
+	case func(any) string:
+		c.Format = func(value any) any { return format(value) }
+	case func(any) template.HTML:
+		c.Format = func(value any) any { return format(value) }
+	default:
+		return TableColumn{}, fmt.Errorf("table: unsupported format type %T of column %s", format, c.Key)
+	}
+
+	return c, nil
+}
+
+func tableKeys(row reflect.Value) []string {
+	row = indirect(row)
+
+	var keys []string
+	switch row.Kind() {
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(row.Type()) {
+			if field.IsExported() && !field.Anonymous {
+				keys = append(keys, field.Name)
+			}
+		}
+	case reflect.Map:
+		for _, key := range row.MapKeys() {
+			keys = append(keys, cast.ToString(key.Interface()))
+		}
+		slices.Sort(keys)
+	}
+	return keys
+}
+
+func tableValue(row reflect.Value, key string) any {
+	for name := range strings.SplitSeq(key, ".") {
+		row = indirect(row)
+
+		switch row.Kind() {
+		case reflect.Struct:
+			row = row.FieldByName(name)
+			if row.IsValid() && !row.CanInterface() {
+				return nil
+			}
+		case reflect.Map:
+			k := reflect.ValueOf(name)
+			if !k.Type().AssignableTo(row.Type().Key()) {
+				if !k.Type().ConvertibleTo(row.Type().Key()) {
+					return nil
+				}
+				k = k.Convert(row.Type().Key())
+			}
+			row = row.MapIndex(k)
+		default:
+			return nil
+		}
+
+		if !row.IsValid() {
+			return nil
+		}
+	}
+	return row.Interface()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
@@ -0,0 +1,124 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tableUser struct {
+	Name    string
+	Email   string
+	Address *tableAddress
+	secret  string
+}
+
+type tableAddress struct {
+	City string
+}
+
+func TestNewTable(t *testing.T) {
+	users := []tableUser{
+		{Name: "Ann", Email: "ann@example.com", Address: &tableAddress{City: "Oslo"}},
+		{Name: "Bob", Email: "bob@example.com", secret: "x"},
+	}
+
+	t.Run("columns", func(t *testing.T) {
+		table, err := NewTable(users, "Name", "Address.City:City", TableColumn{
+			Key:    "Email",
+			Format: func(value any) any { return strings.ToUpper(value.(string)) },
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Name", "City", "Email"}, tableLabels(table))
+		assert.Equal(t, [][]any{
+			{"Ann", "Oslo", "ANN@EXAMPLE.COM"},
+			{"Bob", nil, "BOB@EXAMPLE.COM"},
+		}, table.Rows)
+	})
+
+	t.Run("struct fields", func(t *testing.T) {
+		table, err := NewTable(&users)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Name", "Email", "Address"}, tableLabels(table))
+		assert.Equal(t, "bob@example.com", table.Rows[1][1])
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		rows := []map[string]any{
+			{"id": 1, "title": "First", "meta": map[any]any{"views": 10}},
+			{"id": 2, "title": "Second"},
+		}
+
+		table, err := NewTable(rows, map[string]any{"key": "title", "label": "Title"}, map[any]any{
+			"key":    "meta.views",
+			"format": func(value any) string { return strings.TrimSpace(cast.ToString(value) + " views") },
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Title", "meta.views"}, tableLabels(table))
+		assert.Equal(t, [][]any{{"First", "10 views"}, {"Second", "views"}}, table.Rows)
+
+		table, err = NewTable(rows)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "meta", "title"}, tableLabels(table))
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		table, err := NewTable(nil, "Name")
+		require.NoError(t, err)
+		assert.Empty(t, table.Rows)
+		assert.Len(t, table.Columns, 1)
+
+		table, err = NewTable([]tableUser{})
+		require.NoError(t, err)
+		assert.Empty(t, table.Columns)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewTable("rows")
+		assert.Error(t, err)
+
+		_, err = NewTable(users, 1)
+		assert.Error(t, err)
+
+		_, err = NewTable(users, map[string]any{"key": "Name", "format": "upper"})
+		assert.Error(t, err)
+	})
+}
+
+func tableLabels(table Table) []string {
+	var labels []string
+	for _, column := range table.Columns {
+		labels = append(labels, column.Label)
+	}
+	return labels
+}
+
+func TestTheme_BuiltinTable(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "users", `{{template "components/table.html" (table .Users "Name" (dict "key" "Email" "label" "E-mail" "format" .Mailto))}}`)
+
+	theme := NewTheme("test", store)
+	theme.SetFuncMap(Funcs)
+
+	data := map[string]any{
+		"Users": []tableUser{{Name: "<Ann>", Email: "ann@example.com"}},
+		"Mailto": func(value any) template.HTML {
+			return template.HTML(`<a href="mailto:` + template.HTMLEscapeString(value.(string)) + `">` + template.HTMLEscapeString(value.(string)) + `</a>`)
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.Write(context.Background(), &buf, "users", data))
+
+	out := buf.String()
+	assert.Contains(t, out, `<th scope="col">E-mail</th>`)
+	assert.Contains(t, out, `<td>&lt;Ann&gt;</td>`)
+	assert.Contains(t, out, `<td><a href="mailto:ann@example.com">ann@example.com</a></td>`)
+}
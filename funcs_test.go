@@ -2,6 +2,7 @@ package got
 
 import (
 	"html/template"
+	"io"
 	"testing"
 	"time"
 
@@ -167,6 +168,57 @@ func TestFuncs_Arithmetic_DivisionByZero(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestStrictFuncs_Arithmetic(t *testing.T) {
+	tests := []struct {
+		name     string
+		funcName string
+		inputs   []any
+		expected any
+		err      string
+	}{
+		{"add two ints", "add", []any{2, 3}, int64(5), ""},
+		{"sub multiple ints", "sub", []any{10, 3, 2}, int64(5), ""},
+		{"mul float and int", "mul", []any{2.5, 2}, float64(5), ""},
+		{"single value", "div", []any{42}, 42, ""},
+		{"no values", "add", []any{}, nil, "no operands"},
+		{"division by zero", "div", []any{10, 0}, nil, "can't divide the value by 0"},
+		{"type mismatch", "add", []any{1, "a"}, nil, "1 (int) and a (string)"},
+		{"mismatch after first operation", "mul", []any{2, 3, true}, nil, "6 (int64) and true (bool)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := StrictFuncs[tt.funcName]
+			require.NotNil(t, fn)
+			result, err := fn.(func(...any) (any, error))(tt.inputs...)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStrictFuncs_FailsRender(t *testing.T) {
+	funcs := template.FuncMap{}
+	for k, v := range Funcs {
+		funcs[k] = v
+	}
+	for k, v := range StrictFuncs {
+		funcs[k] = v
+	}
+
+	tpl, err := template.New("page").Funcs(funcs).Parse(`{{div 1 0}}`)
+	require.NoError(t, err)
+
+	err = tpl.Execute(io.Discard, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "can't divide the value by 0")
+}
+
 func TestFuncs_TypeConversions(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1,6 +1,6 @@
 module github.com/gowool/got
 
-go 1.25
+go 1.25.0
 
 require (
 	github.com/davecgh/go-spew v1.1.1
@@ -8,6 +8,8 @@ require (
 	github.com/segmentio/go-snakecase v1.2.0
 	github.com/spf13/cast v1.10.0
 	github.com/stretchr/testify v1.11.1
+	github.com/tdewolff/minify/v2 v2.24.17
+	golang.org/x/text v0.41.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -15,4 +17,5 @@ require (
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
+	github.com/tdewolff/parse/v2 v2.8.16 // indirect
 )
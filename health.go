@@ -0,0 +1,24 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Health pings the stores of the theme and its parents implementing Pinger,
+// so readiness probes can verify the template layer before the instance
+// takes traffic. It returns the joined errors of the unreachable stores.
+func (t *Theme) Health(ctx context.Context) error {
+	var errs []error
+	for theme := t; theme != nil; theme = theme.Parent() {
+		pinger, ok := theme.store.(Pinger)
+		if !ok {
+			continue
+		}
+		if err := pinger.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("theme: store of %s is unhealthy: %w", theme.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,41 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pingStore struct {
+	*StoreMemory
+	err   error
+	pings int
+}
+
+func (s *pingStore) Ping(context.Context) error {
+	s.pings++
+	return s.err
+}
+
+func TestTheme_Health(t *testing.T) {
+	parentStore := &pingStore{StoreMemory: NewStoreMemory()}
+	childStore := &pingStore{StoreMemory: NewStoreMemory()}
+
+	parent := NewTheme("base", parentStore)
+	child := NewTheme("child", childStore)
+	child.SetParent(parent)
+
+	require.NoError(t, child.Health(context.Background()))
+	assert.Equal(t, 1, parentStore.pings)
+	assert.Equal(t, 1, childStore.pings)
+
+	parentStore.err = errors.New("connection refused")
+	err := child.Health(context.Background())
+	assert.EqualError(t, err, "theme: store of base is unhealthy: connection refused")
+	assert.ErrorIs(t, err, parentStore.err)
+
+	assert.NoError(t, NewTheme("memory", NewStoreMemory()).Health(context.Background()))
+}
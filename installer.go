@@ -0,0 +1,273 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultInstallMaxSize is the default size limit of downloaded bundles.
+	DefaultInstallMaxSize = 32 << 20
+
+	// DefaultInstallTimeout is the time limit of the downloads of an
+	// installer without client.
+	DefaultInstallTimeout = time.Minute
+)
+
+// defaultInstallClient downloads the bundles of installers without client.
+// Unlike http.DefaultClient, it doesn't wait forever for a stalled server.
+var defaultInstallClient = &http.Client{Timeout: DefaultInstallTimeout}
+
+// Installer downloads theme bundles and installs them at runtime, e.g. from
+// an admin UI, without restarting:
+//
+//	installer := got.NewInstaller(store, registry, publisherKey)
+//	theme, err := installer.Install(ctx, "https://themes.example.com/shop.tar.gz")
+type Installer struct {
+	// Store receives the templates of installed themes.
+	Store StoreWriter
+
+	// Registry is where installed themes are registered, replacing the
+	// themes of the same name.
+	Registry *ThemeRegistry
+
+	// TrustedKeys are the keys bundles must be signed with, see
+	// ImportSignedBundle.
+	TrustedKeys []ed25519.PublicKey
+
+	// AllowUnsigned installs bundles without verifying their signature.
+	AllowUnsigned bool
+
+	// Client downloads the bundles, a client timing out after
+	// DefaultInstallTimeout if nil.
+	Client *http.Client
+
+	// MaxSize limits the size of downloaded bundles, DefaultInstallMaxSize
+	// if zero.
+	MaxSize int64
+
+	// Configure, if set, is called before an installed theme is registered,
+	// e.g. to set its functions or serve its assets. An error aborts the
+	// installation.
+	Configure func(theme *Theme, bundle *Bundle) error
+
+	mu sync.Mutex
+}
+
+// NewInstaller returns an installer of bundles signed by one of the trusted
+// keys.
+func NewInstaller(store StoreWriter, registry *ThemeRegistry, trusted ...ed25519.PublicKey) *Installer {
+	return &Installer{Store: store, Registry: registry, TrustedKeys: trusted}
+}
+
+// Install downloads the bundle at url and installs it, see InstallBundle.
+func (i *Installer) Install(ctx context.Context, url string) (*Theme, error) {
+	if err := i.validate(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("installer: failed to download %s: %w", url, err)
+	}
+
+	client := i.Client
+	if client == nil {
+		client = defaultInstallClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("installer: failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("installer: failed to download %s: unexpected status %s", url, resp.Status)
+	}
+
+	maxSize := i.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultInstallMaxSize
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("installer: failed to download %s: %w", url, err)
+	}
+	if int64(len(raw)) > maxSize {
+		return nil, fmt.Errorf("installer: failed to download %s: bundle exceeds %d bytes", url, maxSize)
+	}
+
+	return i.InstallBundle(ctx, bytes.NewReader(raw))
+}
+
+// InstallBundle verifies the bundle read from r, saves its templates into
+// the store and registers the theme. Its parent, if any, is resolved by name
+// through the registry.
+//
+// The templates are staged under a namespace of their own, the theme name
+// followed by "@" and a random suffix, so the theme registered under the
+// name is swapped only once every template is saved. The templates of the
+// replaced theme are then removed and its caches cleared. A failed
+// installation leaves the registered theme as it was.
+func (i *Installer) InstallBundle(ctx context.Context, r io.Reader) (*Theme, error) {
+	if err := i.validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		bundle *Bundle
+		err    error
+	)
+	if i.AllowUnsigned {
+		bundle, err = ImportBundle(r)
+	} else {
+		bundle, err = ImportSignedBundle(r, i.TrustedKeys...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("installer: %w", err)
+	}
+
+	name := bundle.Name()
+	if name == "" {
+		return nil, fmt.Errorf("installer: %w: theme without name", ErrInvalidBundle)
+	}
+
+	names, err := bundle.List(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("installer: %w", err)
+	}
+
+	store := &installedStore{store: i.Store, theme: name, namespace: name + "@" + rand.Text()}
+	if err = store.stage(ctx, bundle, names); err != nil {
+		return nil, err
+	}
+
+	theme := NewTheme(name, store)
+	if parent := bundle.Manifest().Parent; parent != "" {
+		theme.SetParentName(i.Registry, parent)
+	}
+
+	if i.Configure != nil {
+		if err = i.Configure(theme, bundle); err != nil {
+			store.remove(context.WithoutCancel(ctx), names)
+			return nil, fmt.Errorf("installer: failed to configure theme %s: %w", name, err)
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	previous, _ := i.Registry.Get(name)
+	i.Registry.Register(theme)
+
+	if previous != nil {
+		previous.Clear()
+		// the templates of a theme installed by an installer of the same store
+		if prev, ok := previous.store.(*installedStore); ok && prev.store == i.Store {
+			if stale, err := prev.List(ctx, name); err == nil {
+				prev.remove(context.WithoutCancel(ctx), stale)
+			}
+		}
+	}
+	return theme, nil
+}
+
+// validate reports an installer missing its store or registry.
+func (i *Installer) validate() error {
+	switch {
+	case i.Store == nil:
+		return errors.New("installer: store is nil")
+	case i.Registry == nil:
+		return errors.New("installer: registry is nil")
+	}
+	return nil
+}
+
+var (
+	_ Store  = (*installedStore)(nil)
+	_ Lister = (*installedStore)(nil)
+	_ Pinger = (*installedStore)(nil)
+)
+
+// installedStore serves the templates of an installed theme from the
+// namespace they are saved under in the store of the installer.
+type installedStore struct {
+	store     StoreWriter
+	theme     string
+	namespace string
+}
+
+// stage saves the templates of the bundle under the namespace, removing
+// those already saved when one fails.
+func (s *installedStore) stage(ctx context.Context, bundle *Bundle, names []string) error {
+	for n, item := range names {
+		tpl, err := bundle.Find(ctx, s.theme, item)
+		if err == nil {
+			err = s.store.Save(ctx, installedTemplate{Template: tpl, theme: s.namespace})
+		}
+		if err != nil {
+			s.remove(context.WithoutCancel(ctx), names[:n])
+			return fmt.Errorf("installer: failed to save template %s/%s: %w", s.theme, item, err)
+		}
+	}
+	return nil
+}
+
+// remove deletes the templates from the namespace, ignoring failures: they
+// are unreachable regardless.
+func (s *installedStore) remove(ctx context.Context, names []string) {
+	for _, name := range names {
+		_ = s.store.Delete(ctx, s.namespace, name)
+	}
+}
+
+func (s *installedStore) Find(ctx context.Context, _, name string) (Template, error) {
+	item, err := s.store.Find(ctx, s.namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if staged, ok := item.(installedTemplate); ok {
+		item = staged.Template
+	}
+	return installedTemplate{Template: item, theme: s.theme}, nil
+}
+
+func (s *installedStore) List(ctx context.Context, _ string) ([]string, error) {
+	lister, ok := s.store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("installer: failed to list templates of %s: %w", s.theme, ErrNotLister)
+	}
+	return lister.List(ctx, s.namespace)
+}
+
+func (s *installedStore) Ping(ctx context.Context) error {
+	if pinger, ok := s.store.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// installedTemplate is a template reporting another theme, the namespace it
+// is saved under or the theme it is served to.
+type installedTemplate struct {
+	Template
+	theme string
+}
+
+func (t installedTemplate) Theme() string {
+	return t.theme
+}
+
+func (t installedTemplate) Stat() TemplateInfo {
+	return Stat(t.Template)
+}
@@ -0,0 +1,249 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportInstallBundle(t *testing.T, key ed25519.PrivateKey, version string, templates map[string]string) []byte {
+	t.Helper()
+
+	store := NewStoreMemory()
+	for name, content := range templates {
+		store.Add("shop", name, content)
+	}
+
+	theme := NewTheme("shop", store)
+	theme.SetParent(NewTheme("base", store))
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.ExportBundle(context.Background(), &buf, BundleOptions{Version: version, SigningKey: key}))
+	return buf.Bytes()
+}
+
+func TestInstaller_Install(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	bundles := map[string][]byte{
+		"/v1.tar.gz": exportInstallBundle(t, private, "1", map[string]string{
+			"index.html":  "<!-- layouts/base.html -->\n" + `{{define "content"}}v1{{end}}`,
+			"legacy.html": "legacy",
+		}),
+		"/v2.tar.gz": exportInstallBundle(t, private, "2", map[string]string{
+			"index.html": "<!-- layouts/base.html -->\n" + `{{define "content"}}v2{{end}}`,
+		}),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bundle, ok := bundles[r.URL.Path]; ok {
+			_, _ = w.Write(bundle)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	base := NewStoreMemory()
+	base.Add("base", "layouts/base.html", `<main>{{block "content" .}}{{end}}</main>`)
+
+	store := NewStoreMemory()
+	registry := NewThemeRegistry(NewTheme("base", base))
+
+	var configured *Bundle
+	installer := NewInstaller(store, registry, public)
+	installer.Configure = func(_ *Theme, bundle *Bundle) error {
+		configured = bundle
+		return nil
+	}
+
+	render := func() string {
+		theme, ok := registry.Get("shop")
+		require.True(t, ok)
+
+		var out strings.Builder
+		require.NoError(t, theme.Write(context.Background(), &out, "index.html", nil))
+		return strings.TrimSpace(out.String())
+	}
+
+	theme, err := installer.Install(context.Background(), server.URL+"/v1.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", theme.Name())
+	assert.Equal(t, "1", configured.Manifest().Version)
+	assert.Equal(t, "<main>v1</main>", render())
+
+	v1 := theme.store.(*installedStore).namespace
+	assert.True(t, strings.HasPrefix(v1, "shop@"))
+
+	theme, err = installer.Install(context.Background(), server.URL+"/v2.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, "<main>v2</main>", render())
+
+	// the templates of v1 are removed
+	names, err := store.List(context.Background(), v1)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	names, err = store.List(context.Background(), theme.store.(*installedStore).namespace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"index.html"}, names)
+
+	names, err = theme.list(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"index.html", "layouts/base.html"}, names)
+
+	_, err = installer.Install(context.Background(), server.URL+"/missing.tar.gz")
+	assert.ErrorContains(t, err, "unexpected status 404")
+
+	installer.MaxSize = 16
+	_, err = installer.Install(context.Background(), server.URL+"/v1.tar.gz")
+	assert.ErrorContains(t, err, "bundle exceeds 16 bytes")
+}
+
+func TestInstaller_InstallBundle(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	signed := exportInstallBundle(t, private, "1", map[string]string{"index.html": "index"})
+	unsigned := exportInstallBundle(t, nil, "1", map[string]string{"index.html": "index"})
+
+	t.Run("untrusted", func(t *testing.T) {
+		registry := NewThemeRegistry()
+		installer := NewInstaller(NewStoreMemory(), registry)
+
+		_, err := installer.InstallBundle(context.Background(), bytes.NewReader(signed))
+		assert.ErrorIs(t, err, ErrUntrustedBundle)
+		assert.Empty(t, registry.Names())
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		store := NewStoreMemory()
+		installer := NewInstaller(store, NewThemeRegistry(NewTheme("base", store)), public)
+
+		_, err := installer.InstallBundle(context.Background(), bytes.NewReader(unsigned))
+		assert.ErrorIs(t, err, ErrUntrustedBundle)
+
+		installer.AllowUnsigned = true
+		theme, err := installer.InstallBundle(context.Background(), bytes.NewReader(unsigned))
+		require.NoError(t, err)
+		assert.Equal(t, "base", theme.Parent().Name())
+	})
+
+	t.Run("configure error", func(t *testing.T) {
+		registry := NewThemeRegistry()
+		store := &savingStore{StoreMemory: NewStoreMemory()}
+		installer := NewInstaller(store, registry, public)
+		installer.Configure = func(*Theme, *Bundle) error {
+			return errors.New("boom")
+		}
+
+		_, err := installer.InstallBundle(context.Background(), bytes.NewReader(signed))
+		assert.EqualError(t, err, "installer: failed to configure theme shop: boom")
+		assert.Empty(t, registry.Names())
+		assert.Empty(t, store.saved)
+	})
+}
+
+// savingStore is a memory store recording its templates by key, failing to
+// save them once it holds limit templates when set.
+type savingStore struct {
+	*StoreMemory
+	saved map[string]bool
+	limit int
+}
+
+func (s *savingStore) Save(ctx context.Context, item Template) error {
+	if s.limit > 0 && len(s.saved) >= s.limit {
+		return errors.New("disk full")
+	}
+	if s.saved == nil {
+		s.saved = make(map[string]bool)
+	}
+	s.saved[item.Theme()+"/"+item.Name()] = true
+	return s.StoreMemory.Save(ctx, item)
+}
+
+func (s *savingStore) Delete(ctx context.Context, theme, name string) error {
+	delete(s.saved, theme+"/"+name)
+	return s.StoreMemory.Delete(ctx, theme, name)
+}
+
+func TestInstaller_InstallBundle_Failure(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	v1 := exportInstallBundle(t, private, "1", map[string]string{"a.html": "a1", "b.html": "b1"})
+	v2 := exportInstallBundle(t, private, "2", map[string]string{"a.html": "a2", "b.html": "b2", "c.html": "c2"})
+
+	store := &savingStore{StoreMemory: NewStoreMemory()}
+	registry := NewThemeRegistry()
+	installer := NewInstaller(store, registry, public)
+
+	live, err := installer.InstallBundle(context.Background(), bytes.NewReader(v1))
+	require.NoError(t, err)
+
+	render := func(name string) string {
+		theme, ok := registry.Get("shop")
+		require.True(t, ok)
+
+		var out strings.Builder
+		require.NoError(t, theme.Write(context.Background(), &out, name, nil))
+		return out.String()
+	}
+	assert.Equal(t, "a1", render("a.html"))
+
+	// the store fails on the second template of v2
+	store.limit = 3
+	_, err = installer.InstallBundle(context.Background(), bytes.NewReader(v2))
+	assert.ErrorContains(t, err, "installer: failed to save template shop/b.html: disk full")
+
+	// v1 is still served, from its own templates only
+	theme, _ := registry.Get("shop")
+	assert.Same(t, live, theme)
+	assert.Equal(t, "a1", render("a.html"))
+	assert.Equal(t, "b1", render("b.html"))
+	assert.Len(t, store.saved, 2)
+	for key := range store.saved {
+		assert.True(t, strings.HasPrefix(key, live.store.(*installedStore).namespace+"/"))
+	}
+}
+
+func TestInstaller_Validate(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	bundle := exportInstallBundle(t, private, "1", map[string]string{"a.html": "a1"})
+
+	tests := []struct {
+		name      string
+		installer *Installer
+		err       string
+	}{
+		{"no store", NewInstaller(nil, NewThemeRegistry(), public), "installer: store is nil"},
+		{"no registry", NewInstaller(NewStoreMemory(), nil, public), "installer: registry is nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.installer.InstallBundle(context.Background(), bytes.NewReader(bundle))
+			assert.EqualError(t, err, tt.err)
+
+			// checked before downloading
+			_, err = tt.installer.Install(context.Background(), "http://127.0.0.1:0/shop.tar.gz")
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestInstaller_Install_DefaultClient(t *testing.T) {
+	assert.Equal(t, DefaultInstallTimeout, defaultInstallClient.Timeout)
+	assert.NotSame(t, http.DefaultClient, defaultInstallClient)
+}
@@ -0,0 +1,119 @@
+package got
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/gowool/got/internal"
+)
+
+var ErrLimitExceeded = errors.New("resource limit exceeded")
+
+// Limits bounds the resources that the expensive functions of Funcs
+// (str_repeat, repeat and seq) may consume while a theme renders a page.
+//
+// A zero field disables the corresponding limit.
+type Limits struct {
+	// MaxRepeat is the maximum count accepted by str_repeat and repeat, and
+	// the maximum length of a sequence produced by seq.
+	MaxRepeat int
+
+	// MaxAlloc is the maximum number of bytes these functions may allocate,
+	// in total, during a single render.
+	MaxAlloc int
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxRepeat > 0 || l.MaxAlloc > 0
+}
+
+// limiter enforces Limits for a single render at a time.
+type limiter struct {
+	limits Limits
+	used   int
+}
+
+func (l *limiter) reset() {
+	l.used = 0
+}
+
+func (l *limiter) repeat(fn string, count int) error {
+	if l.limits.MaxRepeat > 0 && count > l.limits.MaxRepeat {
+		return fmt.Errorf("%s: count %d exceeds %d: %w", fn, count, l.limits.MaxRepeat, ErrLimitExceeded)
+	}
+	return nil
+}
+
+// alloc accounts the allocation of n items of size bytes. The total is
+// checked before it is accounted, so huge counts can't overflow it.
+func (l *limiter) alloc(fn string, n, size int) error {
+	if n < 0 || size < 0 {
+		return fmt.Errorf("%s: negative allocation of %d items of %d bytes", fn, n, size)
+	}
+	if l.limits.MaxAlloc <= 0 {
+		return nil
+	}
+
+	hi, lo := bits.Mul(uint(n), uint(size))
+	total, carry := bits.Add(uint(l.used), lo, 0)
+	switch {
+	case hi != 0 || carry != 0:
+		return fmt.Errorf("%s: allocation of %d items of %d bytes exceeds %d: %w", fn, n, size, l.limits.MaxAlloc, ErrLimitExceeded)
+	case total > uint(l.limits.MaxAlloc):
+		return fmt.Errorf("%s: allocation of %d bytes exceeds %d: %w", fn, total, l.limits.MaxAlloc, ErrLimitExceeded)
+	}
+
+	l.used = int(total)
+	return nil
+}
+
+// funcs returns limited replacements for the functions of funcMap that are
+// subject to Limits. Functions not present in funcMap are not added.
+func (l *limiter) funcs(funcMap template.FuncMap) template.FuncMap {
+	limited := template.FuncMap{
+		"str_repeat": func(s string, count int) (string, error) {
+			if err := l.repeat("str_repeat", count); err != nil {
+				return "", err
+			}
+			if err := l.alloc("str_repeat", count, len(s)); err != nil {
+				return "", err
+			}
+			return strings.Repeat(s, count), nil
+		},
+		"repeat": func(v []any, count int) ([]any, error) {
+			if err := l.repeat("repeat", count); err != nil {
+				return nil, err
+			}
+			if err := l.alloc("repeat", count, len(v)*sizeofAny); err != nil {
+				return nil, err
+			}
+			return slices.Repeat(v, count), nil
+		},
+		"seq": func(args ...int) ([]int, error) {
+			seq := internal.Seq(args...)
+			if err := l.repeat("seq", len(seq)); err != nil {
+				return nil, err
+			}
+			if err := l.alloc("seq", len(seq), sizeofInt); err != nil {
+				return nil, err
+			}
+			return seq, nil
+		},
+	}
+
+	for name := range limited {
+		if _, ok := funcMap[name]; !ok {
+			delete(limited, name)
+		}
+	}
+	return limited
+}
+
+const (
+	sizeofAny = 16
+	sizeofInt = 8
+)
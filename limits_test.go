@@ -0,0 +1,175 @@
+package got
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimits_Enabled(t *testing.T) {
+	assert.False(t, Limits{}.enabled())
+	assert.True(t, Limits{MaxRepeat: 1}.enabled())
+	assert.True(t, Limits{MaxAlloc: 1}.enabled())
+}
+
+func TestLimiter_Funcs(t *testing.T) {
+	l := &limiter{limits: Limits{MaxRepeat: 3, MaxAlloc: 10}}
+
+	funcs := l.funcs(Funcs)
+	require.Len(t, funcs, 3)
+
+	strRepeat := funcs["str_repeat"].(func(string, int) (string, error))
+	repeat := funcs["repeat"].(func([]any, int) ([]any, error))
+	seq := funcs["seq"].(func(...int) ([]int, error))
+
+	s, err := strRepeat("ab", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "ababab", s)
+
+	_, err = strRepeat("a", 4)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Contains(t, err.Error(), "str_repeat: count 4 exceeds 3")
+
+	_, err = strRepeat("abc", 2)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Contains(t, err.Error(), "allocation of 12 bytes exceeds 10")
+
+	l.reset()
+
+	_, err = repeat([]any{1}, 1)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	l.reset()
+	l.limits.MaxAlloc = 0
+
+	v, err := repeat([]any{1}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []any{1, 1}, v)
+
+	ints, err := seq(3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ints)
+
+	_, err = seq(4)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func TestLimiter_Alloc(t *testing.T) {
+	tests := []struct {
+		name    string
+		n, size int
+		wantErr string
+	}{
+		{"within budget", 2, 3, ""},
+		{"exceeds budget", 3, 4, "allocation of 13 bytes exceeds 10"},
+		{"huge count", math.MaxInt, 3, "allocation of 9223372036854775807 items of 3 bytes exceeds 10"},
+		{"huge total", math.MaxInt, 1, "allocation of 9223372036854775808 bytes exceeds 10"},
+		{"negative count", -1, 3, "negative allocation of -1 items of 3 bytes"},
+		{"negative size", 1, -3, "negative allocation of 1 items of -3 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limiter{limits: Limits{MaxAlloc: 10}}
+			l.used = 1
+
+			err := l.alloc("fn", tt.n, tt.size)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, 7, l.used)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+			assert.Equal(t, 1, l.used, "a rejected allocation isn't accounted")
+		})
+	}
+}
+
+func TestLimiter_Funcs_Overflow(t *testing.T) {
+	l := &limiter{limits: Limits{MaxAlloc: 10}}
+	funcs := l.funcs(Funcs)
+
+	strRepeat := funcs["str_repeat"].(func(string, int) (string, error))
+	repeat := funcs["repeat"].(func([]any, int) ([]any, error))
+
+	// len(s)*count wraps negative
+	_, err := strRepeat(strings.Repeat("a", 4), math.MaxInt/2)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	_, err = repeat([]any{1, 2}, math.MaxInt/16)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Zero(t, l.used)
+
+	_, err = strRepeat("a", -1)
+	assert.ErrorContains(t, err, "negative allocation")
+	_, err = strRepeat("ab", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 10, l.used)
+}
+
+func TestLimiter_Funcs_OnlyRegistered(t *testing.T) {
+	l := &limiter{limits: Limits{MaxRepeat: 1}}
+
+	funcs := l.funcs(template.FuncMap{"seq": Funcs["seq"], "upper": strings.ToUpper})
+	assert.Len(t, funcs, 1)
+	assert.Contains(t, funcs, "seq")
+}
+
+func TestTheme_Limits(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{str_repeat "ab" .}}{{str_repeat "ab" .}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+	ctx := context.Background()
+
+	assert.Equal(t, Limits{}, theme.Limits())
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", 1000))
+	assert.Len(t, buf.String(), 4000)
+
+	theme.SetLimits(Limits{MaxRepeat: 100, MaxAlloc: 300})
+	assert.Equal(t, Limits{MaxRepeat: 100, MaxAlloc: 300}, theme.Limits())
+
+	err := theme.Write(ctx, io.Discard, "page", 1000)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	// the allocation budget applies to a whole render and is reset between renders
+	for i := 0; i < 3; i++ {
+		buf.Reset()
+		require.NoError(t, theme.Write(ctx, &buf, "page", 75))
+		assert.Len(t, buf.String(), 300)
+	}
+
+	err = theme.Write(ctx, io.Discard, "page", 76)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func TestTheme_Limits_Deprecated(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{strRepeat "ab" .}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+	theme.AddDeprecatedFuncs(map[string]string{"strRepeat": "str_repeat"})
+	theme.SetLimits(Limits{MaxRepeat: 100, MaxAlloc: 100})
+	ctx := context.Background()
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", 50))
+	assert.Equal(t, strings.Repeat("ab", 50), buf.String())
+
+	// the alias calls the limited function
+	err := theme.Write(ctx, io.Discard, "page", 51)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	err = theme.Write(ctx, io.Discard, "page", 101)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
@@ -0,0 +1,315 @@
+package got
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"maps"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+type localeKey struct{}
+
+// WithLocale returns a context rendering pages in the given locale.
+//
+// The date, date_local, date_utc, number_format and humanize functions of
+// the theme, when it has them, then use localized month and day names,
+// number separators and plurals, and the direction functions follow the
+// locale. A template set is compiled per locale.
+func WithLocale(ctx context.Context, locale language.Tag) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom returns the locale set by WithLocale.
+func LocaleFrom(ctx context.Context) (language.Tag, bool) {
+	locale, ok := ctx.Value(localeKey{}).(language.Tag)
+	return locale, ok
+}
+
+// LocaleNames are the localized words of the date and humanize functions.
+type LocaleNames struct {
+	Months      [12]string
+	ShortMonths [12]string
+	// Days and ShortDays start on Sunday, as time.Weekday.
+	Days      [7]string
+	ShortDays [7]string
+
+	// Units maps the units of humanize, "minute", "hour", "day", "month"
+	// and "year", to their phrase by plural form, %d standing for the
+	// count:
+	//
+	//	"day": {plural.One: "%d day", plural.Other: "%d days"}
+	Units map[string]map[plural.Form]string
+
+	// Past and Future wrap a unit phrase, %s standing for the phrase, e.g.
+	// "%s ago" and "in %s".
+	Past   string
+	Future string
+
+	// Now is the phrase of times less than a minute away.
+	Now string
+}
+
+func (n *LocaleNames) unit(locale language.Tag, unit string, count int) string {
+	forms := n.Units[unit]
+	phrase, ok := forms[plural.Cardinal.MatchPlural(locale, count, 0, 0, 0, 0)]
+	if !ok {
+		phrase = forms[plural.Other]
+	}
+	return fmt.Sprintf(phrase, count)
+}
+
+var localeNames sync.Map
+
+// RegisterLocaleNames sets the names used for the locale and, unless a
+// region or script is given, the locales of the same language. Names of
+// English, French, German and Spanish are built in, other locales fall
+// back to English.
+func RegisterLocaleNames(locale language.Tag, names LocaleNames) {
+	localeNames.Store(locale.String(), &names)
+}
+
+func lookupLocaleNames(locale language.Tag) *LocaleNames {
+	if names, ok := localeNames.Load(locale.String()); ok {
+		return names.(*LocaleNames)
+	}
+	if base, confidence := locale.Base(); confidence != language.No {
+		if names, ok := localeNames.Load(base.String()); ok {
+			return names.(*LocaleNames)
+		}
+	}
+	names, _ := localeNames.Load(language.English.String())
+	return names.(*LocaleNames)
+}
+
+// LocaleFuncs returns the date, date_local, date_utc, number_format and
+// humanize functions localized for the locale, along with the direction
+// functions of the locale, such as dir and dir_choose.
+func LocaleFuncs(locale language.Tag) template.FuncMap {
+	funcs := bidiFuncs(locale)
+	maps.Copy(funcs, template.FuncMap{
+		"date": func(layout string, date any, location string) string {
+			return FormatDateLocale(locale, layout, date, location)
+		},
+		"date_local": func(layout string, date any) string {
+			return FormatDateLocale(locale, layout, date, "Local")
+		},
+		"date_utc": func(layout string, date any) string {
+			return FormatDateLocale(locale, layout, date, "UTC")
+		},
+		"number_format": func(value any, decimals ...int) string {
+			return FormatNumber(locale, value, decimals...)
+		},
+		"humanize": func(date any) string {
+			return Humanize(locale, date, time.Now())
+		},
+	})
+	return funcs
+}
+
+// localeFuncs returns funcs with the functions of LocaleFuncs it has
+// replaced by the ones of the locale.
+func localeFuncs(funcs template.FuncMap, locale language.Tag) template.FuncMap {
+	localized := maps.Clone(funcs)
+	for name, fn := range LocaleFuncs(locale) {
+		if _, ok := localized[name]; ok {
+			localized[name] = fn
+		}
+	}
+	return localized
+}
+
+// FormatDateLocale formats the date like FormatDate, with the month and day
+// names of the locale.
+func FormatDateLocale(locale language.Tag, layout string, date any, location string) string {
+	t := dateIn(date, location)
+	names := lookupLocaleNames(locale)
+
+	var b strings.Builder
+	for layout != "" {
+		i, token := nextNameToken(layout)
+		if i < 0 {
+			b.WriteString(t.Format(layout))
+			break
+		}
+		if i > 0 {
+			b.WriteString(t.Format(layout[:i]))
+		}
+
+		switch token {
+		case "January":
+			b.WriteString(names.Months[t.Month()-1])
+		case "Jan":
+			b.WriteString(names.ShortMonths[t.Month()-1])
+		case "Monday":
+			b.WriteString(names.Days[t.Weekday()])
+		case "Mon":
+			b.WriteString(names.ShortDays[t.Weekday()])
+		}
+		layout = layout[i+len(token):]
+	}
+	return b.String()
+}
+
+// nextNameToken returns the first month or day name token of a time layout
+// and its index, -1 if there is none. Tokens are matched the way the time
+// package does, "Jan" and "Mon" not followed by a lower case letter.
+func nextNameToken(layout string) (int, string) {
+	for i := 0; i+3 <= len(layout); i++ {
+		var short, long string
+		switch layout[i : i+3] {
+		case "Jan":
+			short, long = "Jan", "January"
+		case "Mon":
+			short, long = "Mon", "Monday"
+		default:
+			continue
+		}
+
+		if strings.HasPrefix(layout[i:], long) {
+			return i, long
+		}
+		if rest := layout[i+3:]; rest == "" || rest[0] < 'a' || rest[0] > 'z' {
+			return i, short
+		}
+	}
+	return -1, ""
+}
+
+// FormatNumber formats the number with the separators of the locale and,
+// if given, a fixed number of decimals. Values not convertible to a number
+// format as 0.
+func FormatNumber(locale language.Tag, value any, decimals ...int) string {
+	var opts []number.Option
+	if len(decimals) > 0 {
+		opts = append(opts, number.MinFractionDigits(decimals[0]), number.MaxFractionDigits(decimals[0]))
+	}
+
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+	default:
+		value = cast.ToFloat64(value)
+	}
+
+	return message.NewPrinter(locale).Sprint(number.Decimal(value, opts...))
+}
+
+// Humanize describes the time relative to now, such as "3 days ago" or
+// "in 2 hours", in the language of the locale.
+func Humanize(locale language.Tag, date any, now time.Time) string {
+	names := lookupLocaleNames(locale)
+
+	d := now.Sub(toTime(date))
+	phrase := names.Past
+	if d < 0 {
+		d, phrase = -d, names.Future
+	}
+
+	const (
+		day   = 24 * time.Hour
+		month = 30 * day
+		year  = 365 * day
+	)
+
+	var (
+		unit string
+		size time.Duration
+	)
+	switch {
+	case d < 45*time.Second:
+		return names.Now
+	case d < 45*time.Minute:
+		unit, size = "minute", time.Minute
+	case d < 22*time.Hour:
+		unit, size = "hour", time.Hour
+	case d < 26*day:
+		unit, size = "day", day
+	case d < 320*day:
+		unit, size = "month", month
+	default:
+		unit, size = "year", year
+	}
+
+	count := max(int(math.Round(float64(d)/float64(size))), 1)
+	return fmt.Sprintf(phrase, names.unit(locale, unit, count))
+}
+
+func init() {
+	RegisterLocaleNames(language.English, LocaleNames{
+		Months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Days:        [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		ShortDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		Units: map[string]map[plural.Form]string{
+			"minute": {plural.One: "%d minute", plural.Other: "%d minutes"},
+			"hour":   {plural.One: "%d hour", plural.Other: "%d hours"},
+			"day":    {plural.One: "%d day", plural.Other: "%d days"},
+			"month":  {plural.One: "%d month", plural.Other: "%d months"},
+			"year":   {plural.One: "%d year", plural.Other: "%d years"},
+		},
+		Past:   "%s ago",
+		Future: "in %s",
+		Now:    "just now",
+	})
+
+	RegisterLocaleNames(language.French, LocaleNames{
+		Months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		Units: map[string]map[plural.Form]string{
+			"minute": {plural.One: "%d minute", plural.Other: "%d minutes"},
+			"hour":   {plural.One: "%d heure", plural.Other: "%d heures"},
+			"day":    {plural.One: "%d jour", plural.Other: "%d jours"},
+			"month":  {plural.One: "%d mois", plural.Other: "%d mois"},
+			"year":   {plural.One: "%d an", plural.Other: "%d ans"},
+		},
+		Past:   "il y a %s",
+		Future: "dans %s",
+		Now:    "à l’instant",
+	})
+
+	RegisterLocaleNames(language.German, LocaleNames{
+		Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		// dative, as in "vor 3 Tagen" and "in 3 Tagen"
+		Units: map[string]map[plural.Form]string{
+			"minute": {plural.One: "%d Minute", plural.Other: "%d Minuten"},
+			"hour":   {plural.One: "%d Stunde", plural.Other: "%d Stunden"},
+			"day":    {plural.One: "%d Tag", plural.Other: "%d Tagen"},
+			"month":  {plural.One: "%d Monat", plural.Other: "%d Monaten"},
+			"year":   {plural.One: "%d Jahr", plural.Other: "%d Jahren"},
+		},
+		Past:   "vor %s",
+		Future: "in %s",
+		Now:    "gerade eben",
+	})
+
+	RegisterLocaleNames(language.Spanish, LocaleNames{
+		Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		Units: map[string]map[plural.Form]string{
+			"minute": {plural.One: "%d minuto", plural.Other: "%d minutos"},
+			"hour":   {plural.One: "%d hora", plural.Other: "%d horas"},
+			"day":    {plural.One: "%d día", plural.Other: "%d días"},
+			"month":  {plural.One: "%d mes", plural.Other: "%d meses"},
+			"year":   {plural.One: "%d año", plural.Other: "%d años"},
+		},
+		Past:   "hace %s",
+		Future: "dentro de %s",
+		Now:    "ahora mismo",
+	})
+}
@@ -0,0 +1,135 @@
+package got
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+func TestFormatDateLocale(t *testing.T) {
+	date := time.Date(2024, time.March, 4, 15, 4, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		locale language.Tag
+		layout string
+		want   string
+	}{
+		{name: "english", locale: language.English, layout: "Monday, 2 January 2006", want: "Monday, 4 March 2024"},
+		{name: "french", locale: language.French, layout: "Monday 2 January 2006", want: "lundi 4 mars 2024"},
+		{name: "german short", locale: language.German, layout: "Mon, 02. Jan 2006 15:04", want: "Mo., 04. März 2024 15:04"},
+		{name: "spanish region", locale: language.MustParse("es-MX"), layout: "2 January", want: "4 marzo"},
+		{name: "unknown locale", locale: language.Japanese, layout: "Jan 2", want: "Mar 4"},
+		{name: "not a token", locale: language.French, layout: "Month Janet", want: "Month Janet"},
+		{name: "no names", locale: language.French, layout: "2006-01-02", want: "2024-03-04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatDateLocale(tt.locale, tt.layout, date, "UTC"))
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   language.Tag
+		value    any
+		decimals []int
+		want     string
+	}{
+		{name: "english", locale: language.English, value: 1234567.891, decimals: []int{2}, want: "1,234,567.89"},
+		{name: "german", locale: language.German, value: 1234567.891, decimals: []int{2}, want: "1.234.567,89"},
+		{name: "integer", locale: language.English, value: 1234, want: "1,234"},
+		{name: "string", locale: language.English, value: "1234.5", decimals: []int{1}, want: "1,234.5"},
+		{name: "invalid", locale: language.English, value: "abc", want: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatNumber(tt.locale, tt.value, tt.decimals...))
+		})
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	now := time.Date(2024, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		locale language.Tag
+		date   time.Time
+		want   string
+	}{
+		{name: "now", locale: language.English, date: now.Add(-10 * time.Second), want: "just now"},
+		{name: "minute", locale: language.English, date: now.Add(-time.Minute), want: "1 minute ago"},
+		{name: "hours", locale: language.English, date: now.Add(-3 * time.Hour), want: "3 hours ago"},
+		{name: "future", locale: language.English, date: now.Add(49 * time.Hour), want: "in 2 days"},
+		{name: "months", locale: language.English, date: now.AddDate(0, -2, 0), want: "2 months ago"},
+		{name: "years", locale: language.English, date: now.AddDate(-3, 0, 0), want: "3 years ago"},
+		{name: "french", locale: language.French, date: now.Add(-3 * 24 * time.Hour), want: "il y a 3 jours"},
+		{name: "german", locale: language.German, date: now.Add(-24 * time.Hour), want: "vor 1 Tag"},
+		{name: "spanish", locale: language.Spanish, date: now.Add(2 * time.Hour), want: "dentro de 2 horas"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Humanize(tt.locale, tt.date, now))
+		})
+	}
+}
+
+func TestRegisterLocaleNames(t *testing.T) {
+	polish := language.Polish
+	names := *lookupLocaleNames(language.English)
+	names.Units = map[string]map[plural.Form]string{
+		"day": {plural.One: "%d dzień", plural.Few: "%d dni", plural.Many: "%d dni"},
+	}
+	names.Past = "%s temu"
+	RegisterLocaleNames(polish, names)
+
+	now := time.Now()
+	assert.Equal(t, "1 dzień temu", Humanize(polish, now.Add(-24*time.Hour), now))
+	assert.Equal(t, "5 dni temu", Humanize(polish, now.Add(-5*24*time.Hour), now))
+}
+
+func TestTheme_Write_Locale(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{date_utc "2 January 2006" .Date}} {{number_format .Total 2}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+
+	data := map[string]any{"Date": time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), "Total": 1234.5}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "default", ctx: context.Background(), want: "1 May 2024 1,234.50"},
+		{name: "german", ctx: WithLocale(context.Background(), language.German), want: "1 Mai 2024 1.234,50"},
+		{name: "french", ctx: WithLocale(context.Background(), language.French), want: "1 mai 2024 1\u00a0234,50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			require.NoError(t, theme.Write(tt.ctx, &out, "page", data))
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	locale, ok := LocaleFrom(WithLocale(context.Background(), language.German))
+	assert.True(t, ok)
+	assert.Equal(t, language.German, locale)
+	_, ok = LocaleFrom(context.Background())
+	assert.False(t, ok)
+}
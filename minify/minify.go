@@ -0,0 +1,70 @@
+// Package minify provides a got.PostProcessor minifying rendered pages with
+// github.com/tdewolff/minify.
+package minify
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+
+	"github.com/gowool/got"
+	tdminify "github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+)
+
+var _ got.PostProcessor = (*Processor)(nil)
+
+// Processor minifies the HTML output of a theme along with its inline
+// styles, scripts, SVG and JSON:
+//
+//	theme.AddPostProcessor(minify.New("feeds/*", "*.txt"))
+type Processor struct {
+	m    *tdminify.M
+	skip []string
+}
+
+// New returns a processor minifying every page except the ones whose
+// template name matches one of the skip patterns, in the path.Match syntax.
+//
+// Document and end tags are kept, so that minified pages keep their
+// structure.
+func New(skip ...string) *Processor {
+	m := tdminify.New()
+	m.Add("text/html", &html.Minifier{KeepDocumentTags: true, KeepEndTags: true})
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFuncRegexp(regexp.MustCompile(`^(application|text)/(x-)?(java|ecma)script$`), js.Minify)
+	m.AddFuncRegexp(regexp.MustCompile(`[/+]json$`), json.Minify)
+
+	return &Processor{m: m, skip: slices.Clone(skip)}
+}
+
+// Minifier returns the underlying minifier, to customize the minifier of a
+// media type.
+func (p *Processor) Minifier() *tdminify.M {
+	return p.m
+}
+
+func (p *Processor) Process(_ context.Context, name string, out []byte) ([]byte, error) {
+	for _, pattern := range p.skip {
+		matched, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("minify: invalid skip pattern %q: %w", pattern, err)
+		}
+		if matched {
+			return out, nil
+		}
+	}
+
+	minified, err := p.m.Bytes("text/html", out)
+	if err != nil {
+		return nil, fmt.Errorf("minify: failed to minify %s: %w", name, err)
+	}
+	return minified, nil
+}
@@ -0,0 +1,60 @@
+package minify
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gowool/got"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessor_Process(t *testing.T) {
+	page := []byte("<html>\n  <head>\n    <style>\n      p { color: #ff0000; }\n    </style>\n  </head>\n" +
+		"  <body>\n    <p class=\"text\">  Hello  </p>\n    <script>\n      var answer = 40 + 2;\n    </script>\n  </body>\n</html>\n")
+
+	tests := []struct {
+		name string
+		skip []string
+		page string
+		want string
+	}{
+		{
+			name: "minified",
+			page: "index.html",
+			want: `<html><head><style>p{color:red}</style></head><body><p class=text>Hello</p><script>var answer=40+2</script></body></html>`,
+		},
+		{
+			name: "skipped",
+			skip: []string{"feeds/*"},
+			page: "feeds/rss.xml",
+			want: string(page),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := New(tt.skip...).Process(context.Background(), tt.page, page)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(out))
+		})
+	}
+}
+
+func TestProcessor_InvalidPattern(t *testing.T) {
+	_, err := New("[").Process(context.Background(), "index.html", []byte("<p>x</p>"))
+	assert.Error(t, err)
+}
+
+func TestProcessor_Theme(t *testing.T) {
+	store := got.NewStoreMemory()
+	store.Add("test", "index.html", "<div>\n  <p>{{.}}</p>\n</div>\n")
+
+	theme := got.NewTheme("test", store)
+	theme.AddPostProcessor(New())
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.Write(context.Background(), &buf, "index.html", "hi"))
+	assert.Equal(t, "<div><p>hi</p></div>", buf.String())
+}
@@ -0,0 +1,163 @@
+package got
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compressor compresses rendered pages for a Content-Encoding.
+type Compressor interface {
+	// Encoding returns the Content-Encoding token, such as "gzip" or "br".
+	Encoding() string
+	Compress(body []byte) ([]byte, error)
+}
+
+type compressor struct {
+	encoding string
+	compress func(body []byte) ([]byte, error)
+}
+
+func (c compressor) Encoding() string {
+	return c.encoding
+}
+
+func (c compressor) Compress(body []byte) ([]byte, error) {
+	return c.compress(body)
+}
+
+// NewCompressor returns a Compressor for the encoding, e.g. brotli from a
+// third-party package:
+//
+//	got.NewCompressor("br", func(body []byte) ([]byte, error) { ... })
+func NewCompressor(encoding string, compress func(body []byte) ([]byte, error)) Compressor {
+	return compressor{encoding: encoding, compress: compress}
+}
+
+// GzipCompressor returns a gzip Compressor using the compression level.
+func GzipCompressor(level int) Compressor {
+	return NewCompressor("gzip", func(body []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w, err := gzip.NewWriterLevel(&buf, level)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(body); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+type pageVariant struct {
+	encoding string
+	body     []byte
+}
+
+// Page is a rendered page along with its compressed variants, so that it
+// can be served repeatedly without being compressed per request. A Page
+// must not be modified.
+type Page struct {
+	Body     []byte
+	variants []pageVariant
+}
+
+// NewPage compresses body with each compressor, in order of preference.
+func NewPage(body []byte, compressors ...Compressor) (*Page, error) {
+	p := &Page{Body: body}
+	for _, c := range compressors {
+		compressed, err := c.Compress(body)
+		if err != nil {
+			return nil, fmt.Errorf("page: failed to compress with %s: %w", c.Encoding(), err)
+		}
+		p.variants = append(p.variants, pageVariant{encoding: c.Encoding(), body: compressed})
+	}
+	return p, nil
+}
+
+// Encodings returns the encodings of the compressed variants.
+func (p *Page) Encodings() []string {
+	encodings := make([]string, len(p.variants))
+	for i, v := range p.variants {
+		encodings[i] = v.encoding
+	}
+	return encodings
+}
+
+// Variant returns the body compressed with the encoding.
+func (p *Page) Variant(encoding string) ([]byte, bool) {
+	for _, v := range p.variants {
+		if strings.EqualFold(v.encoding, encoding) {
+			return v.body, true
+		}
+	}
+	return nil, false
+}
+
+// Negotiate returns the variant preferred by the Accept-Encoding header,
+// ties broken by the order of the compressors, and its encoding. The
+// uncompressed body is returned with an empty encoding when no variant is
+// acceptable.
+//
+//	body, encoding := page.Negotiate(r.Header.Get("Accept-Encoding"))
+//	if encoding != "" {
+//		w.Header().Set("Content-Encoding", encoding)
+//	}
+//	w.Header().Add("Vary", "Accept-Encoding")
+//	w.Write(body)
+func (p *Page) Negotiate(acceptEncoding string) (body []byte, encoding string) {
+	accepted := parseAcceptEncoding(acceptEncoding)
+
+	best := -1
+	bestQ := 0.0
+	for i, v := range p.variants {
+		q, ok := accepted[strings.ToLower(v.encoding)]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+
+	if best < 0 {
+		return p.Body, ""
+	}
+	return p.variants[best].body, p.variants[best].encoding
+}
+
+// Size returns the number of bytes held by the page and its variants.
+func (p *Page) Size() int64 {
+	size := int64(len(p.Body))
+	for _, v := range p.variants {
+		size += int64(len(v.encoding) + len(v.body))
+	}
+	return size
+}
+
+func parseAcceptEncoding(header string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for item := range strings.SplitSeq(header, ",") {
+		coding, params, _ := strings.Cut(item, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		for param := range strings.SplitSeq(params, ";") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[coding] = q
+	}
+	return accepted
+}
@@ -0,0 +1,80 @@
+package got
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPage(t *testing.T) {
+	body := bytes.Repeat([]byte("<p>hello</p>"), 100)
+	reverse := NewCompressor("rev", func(body []byte) ([]byte, error) {
+		out := bytes.Clone(body)
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+		return out, nil
+	})
+
+	page, err := NewPage(body, GzipCompressor(gzip.BestSpeed), reverse)
+	require.NoError(t, err)
+	assert.Equal(t, body, page.Body)
+	assert.Equal(t, []string{"gzip", "rev"}, page.Encodings())
+
+	compressed, ok := page.Variant("GZIP")
+	require.True(t, ok)
+	assert.Less(t, len(compressed), len(body))
+
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, body, decompressed)
+
+	_, ok = page.Variant("br")
+	assert.False(t, ok)
+
+	assert.Equal(t, int64(len(body)+len("gzip")+len(compressed)+len("rev")+len(body)), page.Size())
+}
+
+func TestNewPage_Error(t *testing.T) {
+	errCompress := errors.New("compress failed")
+	_, err := NewPage([]byte("x"), NewCompressor("br", func([]byte) ([]byte, error) { return nil, errCompress }))
+	assert.ErrorIs(t, err, errCompress)
+}
+
+func TestPage_Negotiate(t *testing.T) {
+	page := &Page{Body: []byte("identity"), variants: []pageVariant{
+		{encoding: "br", body: []byte("br")},
+		{encoding: "gzip", body: []byte("gzip")},
+	}}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		want           string
+		encoding       string
+	}{
+		{name: "none", acceptEncoding: "", want: "identity"},
+		{name: "gzip", acceptEncoding: "gzip, deflate", want: "gzip", encoding: "gzip"},
+		{name: "server preference", acceptEncoding: "gzip, br", want: "br", encoding: "br"},
+		{name: "client preference", acceptEncoding: "br;q=0.5, gzip;q=0.8", want: "gzip", encoding: "gzip"},
+		{name: "refused", acceptEncoding: "br;q=0, gzip;q=0", want: "identity"},
+		{name: "wildcard", acceptEncoding: "*", want: "br", encoding: "br"},
+		{name: "wildcard exclusion", acceptEncoding: "*;q=0.1, br;q=0", want: "gzip", encoding: "gzip"},
+		{name: "unsupported", acceptEncoding: "zstd", want: "identity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, encoding := page.Negotiate(tt.acceptEncoding)
+			assert.Equal(t, tt.want, string(body))
+			assert.Equal(t, tt.encoding, encoding)
+		})
+	}
+}
@@ -0,0 +1,550 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ PageCache      = (*PageCacheMemory)(nil)
+	_ TagInvalidator = (*PageCacheMemory)(nil)
+)
+
+// CachedPage is a rendered page stored in a PageCache.
+type CachedPage struct {
+	Page   *Page
+	Result RenderResult
+	// Expires is when the page must be rendered again, zero for never.
+	Expires time.Time
+	// StaleUntil is when the page must no longer be served while it is
+	// rendered again, backends may drop the page from then on.
+	StaleUntil time.Time
+}
+
+func (p *CachedPage) expired(now time.Time) bool {
+	return !p.Expires.IsZero() && !now.Before(p.Expires)
+}
+
+// dropped reports whether the page may no longer be served, even stale.
+func (p *CachedPage) dropped(now time.Time) bool {
+	return p.expired(now) && !now.Before(p.StaleUntil)
+}
+
+// PageCache stores whole rendered pages. Backends may drop pages at any
+// time, e.g. once they expire.
+type PageCache interface {
+	Get(ctx context.Context, key string) (*CachedPage, bool, error)
+	Set(ctx context.Context, key string, page *CachedPage) error
+	Delete(ctx context.Context, key string) error
+}
+
+// PageCacheMemory is an in-process PageCache evicting the least recently
+// used pages beyond its budget. Pages that may no longer be served, even
+// stale, are dropped when requested.
+type PageCacheMemory struct {
+	cache *cache[*CachedPage]
+
+	// mu guards the tag index, keys maps tags to the keys of the cached
+	// pages tagged with them.
+	mu   sync.Mutex
+	keys map[string]map[string]*CachedPage
+}
+
+// NewPageCacheMemory returns a memory page cache holding up to budget bytes
+// of pages, zero meaning unlimited.
+func NewPageCacheMemory(budget int64) *PageCacheMemory {
+	c := &PageCacheMemory{
+		cache: newCache(NewCacheBudget(budget), func(p *CachedPage) int64 { return p.Page.Size() }),
+	}
+	c.cache.removed = c.unindex
+	return c
+}
+
+func (c *PageCacheMemory) Get(_ context.Context, key string) (*CachedPage, bool, error) {
+	page, ok := c.cache.Load(key)
+	if ok && page.dropped(time.Now()) {
+		c.cache.DeleteFunc(key, func(p *CachedPage) bool { return p == page })
+		return nil, false, nil
+	}
+	return page, ok, nil
+}
+
+// Set caches the page, indexing it by its tags first, so the index holds
+// every cached page.
+func (c *PageCacheMemory) Set(_ context.Context, key string, page *CachedPage) error {
+	if len(page.Result.Tags) > 0 {
+		c.mu.Lock()
+		if c.keys == nil {
+			c.keys = make(map[string]map[string]*CachedPage)
+		}
+		for _, tag := range page.Result.Tags {
+			if c.keys[tag] == nil {
+				c.keys[tag] = make(map[string]*CachedPage)
+			}
+			c.keys[tag][key] = page
+		}
+		c.mu.Unlock()
+	}
+
+	c.cache.Store(key, page)
+	return nil
+}
+
+// unindex removes the page leaving the cache from the tag index, unless the
+// key was indexed again for a newer page.
+func (c *PageCacheMemory) unindex(key string, page *CachedPage) {
+	if len(page.Result.Tags) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range page.Result.Tags {
+		if keys := c.keys[tag]; keys[key] == page {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(c.keys, tag)
+			}
+		}
+	}
+}
+
+func (c *PageCacheMemory) Delete(_ context.Context, key string) error {
+	c.cache.Delete(key)
+	return nil
+}
+
+// InvalidateTag deletes the pages tagged with tag.
+func (c *PageCacheMemory) InvalidateTag(_ context.Context, tag string) error {
+	c.mu.Lock()
+	keys := maps.Clone(c.keys[tag])
+	c.mu.Unlock()
+
+	for key, page := range keys {
+		c.cache.DeleteFunc(key, func(p *CachedPage) bool { return p == page })
+	}
+	return nil
+}
+
+// Len returns the number of cached pages.
+func (c *PageCacheMemory) Len() int {
+	return c.cache.Len()
+}
+
+// PageCacheOptions configures the page cache of a theme.
+type PageCacheOptions struct {
+	// Backend stores the pages, the page cache is disabled when it is nil.
+	Backend PageCache
+
+	// TTL is how long a page is served from the cache, zero for as long as
+	// the backend keeps it.
+	TTL time.Duration
+
+	// StaleWhileRevalidate is how long after its expiry a page is still
+	// served while it is rendered again in the background, zero to render
+	// expired pages synchronously.
+	StaleWhileRevalidate time.Duration
+
+	// Compressors precompress every cached page, see Page.Negotiate.
+	Compressors []Compressor
+}
+
+// PageCache returns the page cache options of the theme.
+func (t *Theme) PageCache() PageCacheOptions {
+	if options := t.pageCache.Load(); options != nil {
+		return *options
+	}
+	return PageCacheOptions{}
+}
+
+// SetPageCache enables caching of whole pages rendered by RenderPage, so
+// that pages served to anonymous traffic skip execution entirely:
+//
+//	theme.SetPageCache(got.PageCacheOptions{
+//		Backend:     got.NewPageCacheMemory(64 << 20),
+//		TTL:         time.Minute,
+//		Compressors: []got.Compressor{got.GzipCompressor(gzip.BestCompression)},
+//	})
+func (t *Theme) SetPageCache(options PageCacheOptions) {
+	t.pageCache.Store(&options)
+}
+
+// RenderPage renders the named page like Render and returns it along with
+// its compressed variants. With a page cache, the page is served from the
+// cache when possible.
+//
+// The key identifies the variant of the page, such as its URL. When it is
+// empty, a hash of the data is used instead, so the data must be
+// serializable to JSON. Either way, the key must cover everything the page
+// depends on, including what data enrichers add.
+//
+// Pages cached before the theme is reset, by Clear or a change of its
+// settings, are no longer served. They are deleted from backends
+// implementing TagInvalidator, with a tag of the theme the page cache adds
+// to them, and dropped by other backends as they expire or are evicted. In
+// debug mode, the page cache is bypassed.
+func (t *Theme) RenderPage(ctx context.Context, name, key string, data any) (*Page, RenderResult, error) {
+	options := t.PageCache()
+	if options.Backend == nil || t.debug.Load() {
+		p, err := t.renderPage(ctx, name, data, options, new(teeWriter))
+		if err != nil {
+			return nil, RenderResult{}, err
+		}
+		return p.Page, p.Result, nil
+	}
+
+	key, gen, err := t.cacheKey(ctx, name, key, data)
+	if err != nil {
+		return nil, RenderResult{}, err
+	}
+
+	if cached := t.lookupPage(ctx, name, key, gen, data, options); cached != nil {
+		return cached.Page, cached.Result, nil
+	}
+
+	// the render is shared by the concurrent callers, it isn't canceled with
+	// the context of the first one
+	if ctx.Done() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	p, err := t.pages.do(key, func() (*CachedPage, error) {
+		return t.cachePage(ctx, name, key, gen, data, options, new(teeWriter))
+	})
+	if err != nil {
+		return nil, RenderResult{}, err
+	}
+	return p.Page, p.Result, nil
+}
+
+// WritePage writes the named page to w like Render, serving it from the page
+// cache when possible, see RenderPage.
+//
+// On a cache miss, the page is written to w while it is rendered and cached
+// in the same pass, instead of being fully buffered first. The render runs
+// ahead of w, so a slow w holds back neither the render nor the concurrent
+// callers waiting for it; WritePage returns once the page is written to w,
+// or the context is done. If rendering fails, w may have received part of
+// the page, which is not cached. If w fails or the context is done, the
+// error is returned while rendering completes in the background to cache
+// the page.
+//
+// Cached pages are written uncompressed, use RenderPage to negotiate the
+// encoding.
+func (t *Theme) WritePage(ctx context.Context, w io.Writer, name, key string, data any) (RenderResult, error) {
+	options := t.PageCache()
+	if options.Backend == nil || t.debug.Load() {
+		return t.Render(ctx, w, name, data)
+	}
+
+	key, gen, err := t.cacheKey(ctx, name, key, data)
+	if err != nil {
+		return RenderResult{}, err
+	}
+
+	if cached := t.lookupPage(ctx, name, key, gen, data, options); cached != nil {
+		_, err = w.Write(cached.Page.Body)
+		return cached.Result, err
+	}
+
+	call, started := t.pages.start(key)
+	if !started {
+		<-call.done
+		if call.err != nil {
+			return RenderResult{}, call.err
+		}
+		_, err = w.Write(call.value.Page.Body)
+		return call.value.Result, err
+	}
+
+	// the render is shared by the concurrent callers, it isn't canceled with
+	// the context of the first one
+	shared := ctx
+	if ctx.Done() != nil {
+		shared = context.WithoutCancel(ctx)
+	}
+
+	tee := new(teeWriter)
+	go func() {
+		defer func() { tee.close(recover()) }()
+		t.pages.run(key, call, func() (*CachedPage, error) {
+			return t.cachePage(shared, name, key, gen, data, options, tee)
+		})
+	}()
+
+	if err = tee.copyTo(ctx, w); err != nil {
+		return RenderResult{}, err
+	}
+	if tee.panicked != nil {
+		panic(tee.panicked)
+	}
+	if call.err != nil {
+		return RenderResult{}, call.err
+	}
+	return call.value.Result, nil
+}
+
+// cacheKey returns the page cache key of the named page, see RenderPage,
+// along with the generation of the theme it is cached in.
+func (t *Theme) cacheKey(ctx context.Context, name, key string, data any) (string, uint64, error) {
+	if key == "" {
+		var err error
+		if key, err = dataKey(data); err != nil {
+			return "", 0, fmt.Errorf("theme: failed to hash data of template %s/%s: %w", t.name, name, err)
+		}
+	}
+	gen := t.gen.Load()
+	return t.pageKey(gen, cacheName(ctx, name), key), gen, nil
+}
+
+// lookupPage returns the cached page of key unless it is missing or
+// expired. Stale pages are returned while they are rendered again in the
+// background.
+func (t *Theme) lookupPage(ctx context.Context, name, key string, gen uint64, data any, options PageCacheOptions) *CachedPage {
+	cached, ok, err := options.Backend.Get(ctx, key)
+	if err != nil {
+		t.Logger().Warn("got: failed to load cached page", "theme", t.name, "template", name, "error", err)
+		return nil
+	}
+	if !ok {
+		t.emit(ctx, Event{Kind: EventCacheMiss, Template: name, Cache: CachePage})
+		return nil
+	}
+
+	cached = untagPage(cached)
+	now := time.Now()
+	if !cached.expired(now) {
+		t.emit(ctx, Event{Kind: EventCacheHit, Template: name, Cache: CachePage})
+		return cached
+	}
+	if now.Before(cached.StaleUntil) {
+		// the refresh outlives the request
+		ctx = context.WithoutCancel(ctx)
+		t.pages.doAsync(key, func() (*CachedPage, error) {
+			p, err := t.cachePage(ctx, name, key, gen, data, options, new(teeWriter))
+			if err != nil {
+				t.Logger().Warn("got: failed to revalidate cached page", "theme", t.name, "template", name, "error", err)
+			}
+			return p, err
+		})
+		t.emit(ctx, Event{Kind: EventCacheHit, Template: name, Cache: CachePage})
+		return cached
+	}
+
+	t.emit(ctx, Event{Kind: EventCacheMiss, Template: name, Cache: CachePage})
+	return nil
+}
+
+func (t *Theme) cachePage(ctx context.Context, name, key string, gen uint64, data any, options PageCacheOptions, out *teeWriter) (*CachedPage, error) {
+	p, err := t.renderPage(ctx, name, data, options, out)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := *p
+	tagged.Result.Tags = append(slices.Clip(p.Result.Tags), t.pageTag(gen))
+	if err = options.Backend.Set(ctx, key, &tagged); err != nil {
+		t.Logger().Warn("got: failed to cache page", "theme", t.name, "template", name, "error", err)
+	} else if t.gen.Load() != gen {
+		// the theme was reset while rendering, the page may have been
+		// cached after its generation was purged
+		_ = options.Backend.Delete(ctx, key)
+	}
+	return p, nil
+}
+
+func (t *Theme) renderPage(ctx context.Context, name string, data any, options PageCacheOptions, out *teeWriter) (*CachedPage, error) {
+	result, err := t.Render(ctx, out, name, data)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := NewPage(out.buf.Bytes(), options.Compressors...)
+	if err != nil {
+		return nil, fmt.Errorf("theme: failed to compress template %s/%s: %w", t.name, name, err)
+	}
+
+	p := &CachedPage{Page: page, Result: result}
+	if options.TTL > 0 {
+		p.Expires = time.Now().Add(options.TTL)
+		p.StaleUntil = p.Expires.Add(max(options.StaleWhileRevalidate, 0))
+	}
+	return p, nil
+}
+
+// teeWriter buffers the rendered page, which copyTo writes to the writer of
+// a caller while it is rendered.
+type teeWriter struct {
+	mu     sync.Mutex
+	cond   sync.Cond
+	buf    bytes.Buffer
+	closed bool
+
+	// panicked is the value the render panicked with, set by close.
+	panicked any
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cond.Broadcast()
+	return t.buf.Write(p)
+}
+
+// close marks the end of the render, which panicked if recovered isn't nil.
+func (t *teeWriter) close(recovered any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	t.panicked = recovered
+	t.cond.Broadcast()
+}
+
+// copyTo writes the page to w as it is rendered, until the render ends, w
+// fails or ctx is done. The bytes written stay in the buffer, so they are
+// copied outside the lock.
+func (t *teeWriter) copyTo(ctx context.Context, w io.Writer) error {
+	t.mu.Lock()
+	t.cond.L = &t.mu
+	t.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.cond.Broadcast()
+	})
+	defer stop()
+
+	for written := 0; ; {
+		t.mu.Lock()
+		for written == t.buf.Len() && !t.closed && ctx.Err() == nil {
+			t.cond.Wait()
+		}
+		p, closed := t.buf.Bytes()[written:], t.closed
+		t.mu.Unlock()
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(p) == 0 && closed {
+			return nil
+		}
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+		written += len(p)
+	}
+}
+
+// pageKey returns the backend key of a page cached in generation gen of the
+// theme, so pages cached before a reset, e.g. by Clear, are no longer
+// served.
+func (t *Theme) pageKey(gen uint64, name, key string) string {
+	return t.name + "\x00" + strconv.FormatUint(gen, 10) + "\x00" + name + "\x00" + key
+}
+
+// pageTag returns the tag of the pages cached in generation gen of the
+// theme, by which they are purged once the theme is reset. It is the last
+// tag of the cached pages and starts with a NUL byte.
+func (t *Theme) pageTag(gen uint64) string {
+	return "\x00" + t.name + "\x00" + strconv.FormatUint(gen, 10)
+}
+
+// untagPage returns the cached page without the tag of its generation.
+func untagPage(p *CachedPage) *CachedPage {
+	tags := p.Result.Tags
+	if len(tags) == 0 || !strings.HasPrefix(tags[len(tags)-1], "\x00") {
+		return p
+	}
+
+	untagged := *p
+	untagged.Result.Tags = slices.Clip(tags[:len(tags)-1])
+	if len(untagged.Result.Tags) == 0 {
+		untagged.Result.Tags = nil
+	}
+	return &untagged
+}
+
+// purgePages deletes the pages cached in generation gen of the theme from
+// the backend, if it can invalidate tags.
+func (t *Theme) purgePages(gen uint64) {
+	invalidator, ok := t.PageCache().Backend.(TagInvalidator)
+	if !ok {
+		return
+	}
+	if err := invalidator.InvalidateTag(context.Background(), t.pageTag(gen)); err != nil {
+		t.Logger().Warn("got: failed to purge cached pages", "theme", t.name, "error", err)
+	}
+}
+
+func dataKey(data any) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// pageFlight coalesces concurrent renders of the same page.
+type pageFlight struct {
+	mu    sync.Mutex
+	calls map[string]*cacheCall[*CachedPage]
+}
+
+func (f *pageFlight) do(key string, fn func() (*CachedPage, error)) (*CachedPage, error) {
+	call, started := f.start(key)
+	if !started {
+		<-call.done
+		return call.value, call.err
+	}
+
+	f.run(key, call, fn)
+	return call.value, call.err
+}
+
+// doAsync runs fn in a new goroutine unless a call for key is in flight.
+func (f *pageFlight) doAsync(key string, fn func() (*CachedPage, error)) {
+	if call, started := f.start(key); started {
+		go f.run(key, call, fn)
+	}
+}
+
+func (f *pageFlight) start(key string) (*cacheCall[*CachedPage], bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if call, ok := f.calls[key]; ok {
+		return call, false
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]*cacheCall[*CachedPage])
+	}
+	call := &cacheCall[*CachedPage]{done: make(chan struct{}), err: errCacheBuildPanic}
+	f.calls[key] = call
+	return call, true
+}
+
+func (f *pageFlight) run(key string, call *cacheCall[*CachedPage], fn func() (*CachedPage, error)) {
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+}
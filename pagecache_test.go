@@ -0,0 +1,562 @@
+package got
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCacheMemory(t *testing.T) {
+	ctx := context.Background()
+	c := NewPageCacheMemory(0)
+
+	_, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	page := &CachedPage{Page: &Page{Body: []byte("a")}}
+	require.NoError(t, c.Set(ctx, "a", page))
+
+	got, ok, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Same(t, page, got)
+	assert.Equal(t, 1, c.Len())
+
+	require.NoError(t, c.Delete(ctx, "a"))
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestPageCacheMemory_Budget(t *testing.T) {
+	ctx := context.Background()
+	c := NewPageCacheMemory(10)
+
+	require.NoError(t, c.Set(ctx, "a", &CachedPage{Page: &Page{Body: []byte("123456")}}))
+	require.NoError(t, c.Set(ctx, "b", &CachedPage{Page: &Page{Body: []byte("123456")}}))
+
+	_, ok, _ := c.Get(ctx, "a")
+	assert.False(t, ok)
+	_, ok, _ = c.Get(ctx, "b")
+	assert.True(t, ok)
+}
+
+func TestPageCacheMemory_Expired(t *testing.T) {
+	ctx := context.Background()
+	c := NewPageCacheMemory(0)
+
+	now := time.Now()
+	stale := &CachedPage{Page: &Page{Body: []byte("a")}, Expires: now.Add(-time.Minute), StaleUntil: now.Add(time.Minute)}
+	dropped := &CachedPage{Page: &Page{Body: []byte("b")}, Expires: now.Add(-time.Minute), StaleUntil: now.Add(-time.Minute)}
+	require.NoError(t, c.Set(ctx, "a", stale))
+	require.NoError(t, c.Set(ctx, "b", dropped))
+
+	got, ok, _ := c.Get(ctx, "a")
+	assert.True(t, ok, "stale pages are served while revalidated")
+	assert.Same(t, stale, got)
+
+	_, ok, _ = c.Get(ctx, "b")
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestPageCacheMemory_TagIndex(t *testing.T) {
+	ctx := context.Background()
+	tagged := func(body string, tags ...string) *CachedPage {
+		return &CachedPage{Page: &Page{Body: []byte(body)}, Result: RenderResult{Tags: tags}}
+	}
+
+	c := NewPageCacheMemory(10)
+	require.NoError(t, c.Set(ctx, "a", tagged("12345", "nav", "product:1")))
+	require.NoError(t, c.Set(ctx, "b", tagged("12345", "nav")))
+	assert.Len(t, c.keys["nav"], 2)
+
+	// evicted
+	require.NoError(t, c.Set(ctx, "c", tagged("12345", "nav")))
+	assert.NotContains(t, c.keys["nav"], "a")
+	assert.NotContains(t, c.keys, "product:1")
+
+	// deleted
+	require.NoError(t, c.Delete(ctx, "b"))
+	assert.Equal(t, []string{"c"}, slices.Collect(maps.Keys(c.keys["nav"])))
+
+	// replaced by a page with other tags
+	require.NoError(t, c.Set(ctx, "c", tagged("1", "footer")))
+	assert.NotContains(t, c.keys, "nav")
+	assert.Contains(t, c.keys["footer"], "c")
+
+	// dropped once expired
+	page := tagged("1", "footer")
+	page.Expires = time.Now().Add(-time.Minute)
+	require.NoError(t, c.Set(ctx, "c", page))
+	_, ok, _ := c.Get(ctx, "c")
+	assert.False(t, ok)
+	assert.Empty(t, c.keys)
+
+	// a key tagged again isn't invalidated by its previous tags
+	require.NoError(t, c.Set(ctx, "d", tagged("1", "nav")))
+	require.NoError(t, c.Set(ctx, "d", tagged("2", "footer")))
+	require.NoError(t, c.InvalidateTag(ctx, "nav"))
+	assert.Equal(t, 1, c.Len())
+	require.NoError(t, c.InvalidateTag(ctx, "footer"))
+	assert.Equal(t, 0, c.Len())
+	assert.Empty(t, c.keys)
+}
+
+// countingEnricher counts the renders of a theme.
+func countingEnricher(n *atomic.Int32) DataEnricher {
+	return DataEnricherFunc(func(_ context.Context, data any) (any, error) {
+		n.Add(1)
+		return data, nil
+	})
+}
+
+func TestTheme_RenderPage(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p><script>go()</script>`)
+
+	theme := NewTheme("test", store)
+	theme.SetCSPHashes(true)
+
+	var renders atomic.Int32
+	theme.AddDataEnricher(countingEnricher(&renders))
+
+	t.Run("without cache", func(t *testing.T) {
+		page, result, err := theme.RenderPage(ctx, "page", "", "a")
+		require.NoError(t, err)
+		assert.Equal(t, "<p>a</p><script>go()</script>", string(page.Body))
+		assert.Equal(t, []string{cspHash("go()")}, result.ScriptHashes)
+
+		_, _, err = theme.RenderPage(ctx, "page", "", "a")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), renders.Load())
+	})
+
+	backend := NewPageCacheMemory(0)
+	theme.SetPageCache(PageCacheOptions{
+		Backend:     backend,
+		TTL:         time.Hour,
+		Compressors: []Compressor{GzipCompressor(gzip.BestSpeed)},
+	})
+	assert.Same(t, backend, theme.PageCache().Backend)
+	renders.Store(0)
+
+	t.Run("data key", func(t *testing.T) {
+		page, result, err := theme.RenderPage(ctx, "page", "", "a")
+		require.NoError(t, err)
+		assert.Equal(t, "<p>a</p><script>go()</script>", string(page.Body))
+		assert.Equal(t, []string{"gzip"}, page.Encodings())
+		assert.Equal(t, []string{cspHash("go()")}, result.ScriptHashes)
+
+		cached, _, err := theme.RenderPage(ctx, "page", "", "a")
+		require.NoError(t, err)
+		assert.Same(t, page, cached)
+		assert.Equal(t, int32(1), renders.Load())
+
+		other, _, err := theme.RenderPage(ctx, "page", "", "b")
+		require.NoError(t, err)
+		assert.Equal(t, "<p>b</p><script>go()</script>", string(other.Body))
+		assert.Equal(t, int32(2), renders.Load())
+	})
+
+	t.Run("caller key", func(t *testing.T) {
+		page, _, err := theme.RenderPage(ctx, "page", "/x", "c")
+		require.NoError(t, err)
+
+		cached, _, err := theme.RenderPage(ctx, "page", "/x", "ignored")
+		require.NoError(t, err)
+		assert.Same(t, page, cached)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		key := theme.pageKey(theme.gen.Load(), "page", "/expired")
+		require.NoError(t, backend.Set(ctx, key, &CachedPage{Page: &Page{Body: []byte("old")}, Expires: time.Now().Add(-time.Second)}))
+
+		page, _, err := theme.RenderPage(ctx, "page", "/expired", "new")
+		require.NoError(t, err)
+		assert.Equal(t, "<p>new</p><script>go()</script>", string(page.Body))
+	})
+
+	t.Run("unserializable data", func(t *testing.T) {
+		_, _, err := theme.RenderPage(ctx, "page", "", func() {})
+		assert.Error(t, err)
+	})
+
+	t.Run("render error is not cached", func(t *testing.T) {
+		_, _, err := theme.RenderPage(ctx, "missing", "/missing", nil)
+		assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+		_, ok, _ := backend.Get(ctx, theme.pageKey(theme.gen.Load(), "missing", "/missing"))
+		assert.False(t, ok)
+	})
+}
+
+type failingPageCache struct{}
+
+func (failingPageCache) Get(context.Context, string) (*CachedPage, bool, error) {
+	return nil, false, errors.New("get failed")
+}
+
+func (failingPageCache) Set(context.Context, string, *CachedPage) error {
+	return errors.New("set failed")
+}
+
+func (failingPageCache) Delete(context.Context, string) error {
+	return errors.New("delete failed")
+}
+
+func TestTheme_RenderPage_BackendErrors(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: failingPageCache{}})
+
+	page, _, err := theme.RenderPage(context.Background(), "page", "k", "a")
+	require.NoError(t, err, "backend errors only degrade to rendering")
+	assert.Equal(t, "<p>a</p>", string(page.Body))
+}
+
+func TestTheme_RenderPage_Reset(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	backend := NewPageCacheMemory(0)
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: backend, TTL: time.Hour})
+
+	var renders atomic.Int32
+	theme.AddDataEnricher(countingEnricher(&renders))
+
+	t.Run("clear", func(t *testing.T) {
+		for range 2 {
+			_, _, err := theme.RenderPage(ctx, "page", "k", "a")
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int32(1), renders.Load())
+
+		store.Add("test", "page", `<b>{{.}}</b>`)
+		theme.Clear()
+
+		page, _, err := theme.RenderPage(ctx, "page", "k", "a")
+		require.NoError(t, err)
+		assert.Equal(t, "<b>a</b>", string(page.Body))
+		assert.Equal(t, int32(2), renders.Load())
+	})
+
+	t.Run("purged", func(t *testing.T) {
+		for range 10 {
+			_, _, err := theme.RenderPage(ctx, "page", "k", "a")
+			require.NoError(t, err)
+			assert.Equal(t, 1, backend.Len())
+
+			theme.Clear()
+			assert.Equal(t, 0, backend.Len())
+		}
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		store.Add("test", "tagged", `{{cache_tag "nav"}}<p>{{.}}</p>`)
+
+		_, result, err := theme.RenderPage(ctx, "tagged", "k", "a")
+		require.NoError(t, err)
+		_, cached, err := theme.RenderPage(ctx, "tagged", "k", "a")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"nav"}, result.Tags)
+		assert.Equal(t, result, cached)
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		theme.SetDebug(true)
+		defer theme.SetDebug(false)
+		renders.Store(0)
+		cached := backend.Len()
+
+		for range 2 {
+			_, _, err := theme.RenderPage(ctx, "page", "debug", "a")
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			_, err = theme.WritePage(ctx, &buf, "page", "debug", "a")
+			require.NoError(t, err)
+			assert.Equal(t, "<b>a</b>", buf.String())
+		}
+		assert.Equal(t, int32(4), renders.Load())
+		assert.Equal(t, cached, backend.Len())
+	})
+}
+
+func TestTheme_RenderPage_Coalesces(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: NewPageCacheMemory(0)})
+
+	release := make(chan struct{})
+	var renders atomic.Int32
+	theme.AddDataEnricher(DataEnricherFunc(func(_ context.Context, data any) (any, error) {
+		renders.Add(1)
+		<-release
+		return data, nil
+	}))
+
+	var wg sync.WaitGroup
+	pages := make([]*Page, 5)
+	for i := range pages {
+		wg.Go(func() {
+			page, _, err := theme.RenderPage(context.Background(), "page", "k", "a")
+			assert.NoError(t, err)
+			pages[i] = page
+		})
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), renders.Load())
+	for _, page := range pages {
+		assert.Equal(t, "<p>a</p>", string(page.Body))
+	}
+}
+
+func TestTheme_RenderPage_CanceledCaller(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: NewPageCacheMemory(0)})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	var renders atomic.Int32
+	theme.AddDataEnricher(DataEnricherFunc(func(ctx context.Context, data any) (any, error) {
+		if renders.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return data, ctx.Err()
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, _, err := theme.RenderPage(ctx, "page", "k", "a")
+		leader <- err
+	}()
+	<-started
+	cancel()
+
+	var page *Page
+	waiter := make(chan error)
+	go func() {
+		var err error
+		page, _, err = theme.RenderPage(context.Background(), "page", "k", "a")
+		waiter <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	assert.NoError(t, <-leader)
+	require.NoError(t, <-waiter)
+	assert.Equal(t, "<p>a</p>", string(page.Body))
+	assert.Equal(t, int32(1), renders.Load())
+}
+
+func TestTheme_RenderPage_StaleWhileRevalidate(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	backend := NewPageCacheMemory(0)
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: backend, TTL: time.Hour, StaleWhileRevalidate: time.Hour})
+
+	release := make(chan struct{})
+	var renders atomic.Int32
+	theme.AddDataEnricher(DataEnricherFunc(func(_ context.Context, data any) (any, error) {
+		renders.Add(1)
+		<-release
+		return data, nil
+	}))
+
+	key := theme.pageKey(theme.gen.Load(), "page", "k")
+	stale := &CachedPage{
+		Page:       &Page{Body: []byte("stale")},
+		Expires:    time.Now().Add(-time.Minute),
+		StaleUntil: time.Now().Add(time.Minute),
+	}
+	require.NoError(t, backend.Set(ctx, key, stale))
+
+	for range 3 {
+		page, _, err := theme.RenderPage(ctx, "page", "k", "fresh")
+		require.NoError(t, err)
+		assert.Equal(t, "stale", string(page.Body))
+	}
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		cached, ok, _ := backend.Get(ctx, key)
+		return ok && string(cached.Page.Body) == "<p>fresh</p>"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), renders.Load(), "concurrent revalidations are coalesced")
+
+	cached, _, _ := backend.Get(ctx, key)
+	assert.Equal(t, cached.Expires.Add(time.Hour), cached.StaleUntil)
+
+	t.Run("too stale", func(t *testing.T) {
+		require.NoError(t, backend.Set(ctx, key, &CachedPage{
+			Page:       &Page{Body: []byte("stale")},
+			Expires:    time.Now().Add(-time.Hour),
+			StaleUntil: time.Now().Add(-time.Minute),
+		}))
+
+		page, _, err := theme.RenderPage(ctx, "page", "k", "sync")
+		require.NoError(t, err)
+		assert.Equal(t, "<p>sync</p>", string(page.Body))
+	})
+}
+
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.n++
+	return 0, errors.New("client gone")
+}
+
+func TestTheme_WritePage(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{range .}}<p>{{.}}</p>{{end}}`)
+	store.Add("test", "broken", `a{{index . 5}}`)
+
+	var renders atomic.Int32
+	theme := NewTheme("test", store)
+	theme.AddDataEnricher(countingEnricher(&renders))
+
+	var out strings.Builder
+	_, err := theme.WritePage(ctx, &out, "page", "", []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, "<p>1</p><p>2</p>", out.String())
+	assert.Equal(t, int32(1), renders.Load())
+
+	backend := NewPageCacheMemory(0)
+	theme.SetPageCache(PageCacheOptions{
+		Backend:     backend,
+		Compressors: []Compressor{GzipCompressor(gzip.BestSpeed)},
+	})
+
+	out.Reset()
+	_, err = theme.WritePage(ctx, &out, "page", "/a", []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, "<p>1</p><p>2</p>", out.String())
+	assert.Equal(t, int32(2), renders.Load())
+
+	page, _, err := theme.RenderPage(ctx, "page", "/a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>1</p><p>2</p>", string(page.Body))
+	assert.Equal(t, []string{"gzip"}, page.Encodings())
+	assert.Equal(t, int32(2), renders.Load())
+
+	out.Reset()
+	_, err = theme.WritePage(ctx, &out, "page", "/a", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>1</p><p>2</p>", out.String())
+	assert.Equal(t, int32(2), renders.Load())
+
+	t.Run("client error", func(t *testing.T) {
+		w := new(failingWriter)
+		_, err := theme.WritePage(ctx, w, "page", "/b", []int{3})
+		assert.EqualError(t, err, "client gone")
+		assert.Equal(t, 1, w.n)
+
+		page, _, err := theme.RenderPage(ctx, "page", "/b", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "<p>3</p>", string(page.Body))
+	})
+
+	t.Run("render error", func(t *testing.T) {
+		out.Reset()
+		_, err := theme.WritePage(ctx, &out, "broken", "/c", []int{})
+		var execErr *ExecError
+		assert.ErrorAs(t, err, &execErr)
+		assert.Equal(t, "a", out.String())
+
+		_, ok, _ := backend.Get(ctx, theme.pageKey(theme.gen.Load(), "broken", "/c"))
+		assert.False(t, ok)
+	})
+}
+
+// blockingWriter blocks its writes until release is closed.
+type blockingWriter struct {
+	writing chan struct{}
+	release chan struct{}
+	once    sync.Once
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestTheme_WritePage_SlowClient(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{range .}}<p>{{.}}</p>{{end}}`)
+
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: NewPageCacheMemory(0)})
+
+	t.Run("waiters", func(t *testing.T) {
+		w := &blockingWriter{writing: make(chan struct{}), release: make(chan struct{})}
+		leader := make(chan error)
+		go func() {
+			_, err := theme.WritePage(ctx, w, "page", "/a", []int{1, 2})
+			leader <- err
+		}()
+		<-w.writing
+
+		var out strings.Builder
+		_, err := theme.WritePage(ctx, &out, "page", "/a", []int{1, 2})
+		require.NoError(t, err, "served while the first client is blocked")
+		assert.Equal(t, "<p>1</p><p>2</p>", out.String())
+
+		close(w.release)
+		require.NoError(t, <-leader)
+		assert.Equal(t, "<p>1</p><p>2</p>", w.buf.String())
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		release := make(chan struct{})
+		theme.AddDataEnricher(DataEnricherFunc(func(_ context.Context, data any) (any, error) {
+			<-release
+			return data, nil
+		}))
+		defer close(release)
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		var out strings.Builder
+		_, err := theme.WritePage(ctx, &out, "page", "/b", []int{3})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, out.String())
+	})
+}
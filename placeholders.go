@@ -0,0 +1,178 @@
+package got
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// placeholderFuncs are the functions of late-resolved placeholders. A
+// placeholder outputs the value set for its name anywhere in the render,
+// even after it, such as the page title set by the content template:
+//
+//	<title>{{placeholder "title" "Blog"}}</title>
+//
+//	{{define "content"}}{{set_placeholder "title" .Post.Title}}...{{end}}
+//
+// Pages calling them are rendered into a buffer and the placeholders are
+// filled in a second pass. Placeholders can only be output in HTML text and
+// attribute values, where their values are HTML-escaped, unless they are
+// template.HTML in text. The values can't be escaped for URL, script or
+// style contexts once the page is rendered, so a placeholder output in one
+// of them fails the render.
+var placeholderFuncs = []string{"placeholder", "set_placeholder"}
+
+var (
+	errPlaceholderNotBound = errors.New("placeholder: function is not bound to a render")
+	errPlaceholderContext  = errors.New("placeholder: can only be output in HTML text and attribute values")
+)
+
+// placeholderFn and setPlaceholderFn are the types of the placeholder
+// functions, unbound ones return errPlaceholderNotBound.
+type (
+	placeholderFn    func(name string, fallback ...any) (any, error)
+	setPlaceholderFn func(name string, value any) (string, error)
+)
+
+// defaultPlaceholderFuncs returns the unbound placeholder functions missing
+// from funcMap.
+func defaultPlaceholderFuncs(funcMap template.FuncMap) template.FuncMap {
+	unbound := template.FuncMap{
+		"placeholder":     placeholderFn(func(string, ...any) (any, error) { return "", errPlaceholderNotBound }),
+		"set_placeholder": setPlaceholderFn(func(string, any) (string, error) { return "", errPlaceholderNotBound }),
+	}
+
+	for name := range funcMap {
+		delete(unbound, name)
+	}
+	return unbound
+}
+
+// boundPlaceholderFuncs returns the names of the placeholder functions of
+// funcMap, leaving out the ones replaced by other functions.
+func boundPlaceholderFuncs(funcMap template.FuncMap) []string {
+	var names []string
+	for _, name := range placeholderFuncs {
+		switch funcMap[name].(type) {
+		case placeholderFn, setPlaceholderFn:
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// placeholders holds the values and fallbacks of the placeholders of a
+// render.
+type placeholders struct {
+	values    map[string]any
+	fallbacks map[string]any
+}
+
+func (p *placeholders) reset() {
+	clear(p.values)
+	clear(p.fallbacks)
+}
+
+func (p *placeholders) value(name string) any {
+	if value, ok := p.values[name]; ok {
+		return value
+	}
+	if value, ok := p.fallbacks[name]; ok {
+		return value
+	}
+	return ""
+}
+
+// placeholderProbe follows the marker of a deferred placeholder. html/template
+// escapes it along with the marker, so its escaped form tells the context
+// the placeholder is output in.
+const placeholderProbe = "< "
+
+// placeholderContexts are the forms of the probe in the contexts the values
+// can be escaped for, along with their escaping.
+var placeholderContexts = []struct {
+	probe  string
+	escape func(value any) string
+}{
+	// HTML text
+	{"< ", escapePlaceholder},
+	// quoted attribute values, <title> and <textarea>
+	{"&lt; ", escapePlaceholderText},
+	// unquoted attribute values
+	{"&lt;&#32;", escapePlaceholderUnquoted},
+}
+
+// fill returns the value of the placeholder escaped for the context told by
+// the probe at the start of out, along with the length of the probe.
+func (p *placeholders) fill(name string, out []byte) (string, int, error) {
+	for _, c := range placeholderContexts {
+		if bytes.HasPrefix(out, []byte(c.probe)) {
+			return c.escape(p.value(name)), len(c.probe), nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w, %q is output in a URL, script or style context", errPlaceholderContext, name)
+}
+
+func escapePlaceholder(value any) string {
+	if s, ok := value.(template.HTML); ok {
+		return string(s)
+	}
+	return escapePlaceholderText(value)
+}
+
+func escapePlaceholderText(value any) string {
+	return html.EscapeString(cast.ToString(value))
+}
+
+// unquotedReplacer escapes the characters ending an unquoted attribute
+// value.
+var unquotedReplacer = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;", "=", "&#61;", "`", "&#96;",
+	" ", "&#32;", "\t", "&#9;", "\n", "&#10;", "\f", "&#12;", "\r", "&#13;",
+)
+
+func escapePlaceholderUnquoted(value any) string {
+	return unquotedReplacer.Replace(cast.ToString(value))
+}
+
+// funcs returns the placeholder functions of funcMap bound to p. When
+// deferred, placeholders output markers filled once the render completes,
+// otherwise they output the value set so far.
+func (p *placeholders) funcs(funcMap template.FuncMap, deferred bool) template.FuncMap {
+	bound := template.FuncMap{
+		"placeholder": placeholderFn(func(name string, fallback ...any) (any, error) {
+			if len(fallback) > 0 {
+				if p.fallbacks == nil {
+					p.fallbacks = make(map[string]any)
+				}
+				p.fallbacks[name] = fallback[0]
+			}
+			if deferred {
+				return template.HTML(marker("placeholder:"+name) + placeholderProbe), nil
+			}
+			// html/template escapes the value for its context
+			return p.value(name), nil
+		}),
+		"set_placeholder": setPlaceholderFn(func(name string, value any) (string, error) {
+			if p.values == nil {
+				p.values = make(map[string]any)
+			}
+			p.values[name] = value
+			return "", nil
+		}),
+	}
+
+	names := boundPlaceholderFuncs(funcMap)
+	for name := range bound {
+		if !slices.Contains(names, name) {
+			delete(bound, name)
+		}
+	}
+	return bound
+}
@@ -0,0 +1,118 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholders_Funcs(t *testing.T) {
+	funcs := defaultPlaceholderFuncs(template.FuncMap{})
+	assert.Equal(t, []string{"placeholder", "set_placeholder"}, boundPlaceholderFuncs(funcs))
+
+	_, err := funcs["placeholder"].(placeholderFn)("title")
+	assert.ErrorIs(t, err, errPlaceholderNotBound)
+
+	var p placeholders
+	bound := p.funcs(funcs, false)
+	placeholder := bound["placeholder"].(placeholderFn)
+	set := bound["set_placeholder"].(setPlaceholderFn)
+
+	// html/template escapes the value for its context
+	out, err := placeholder("title", "<Blog>")
+	require.NoError(t, err)
+	assert.Equal(t, "<Blog>", out)
+
+	_, err = set("title", template.HTML("<b>Post</b>"))
+	require.NoError(t, err)
+	out, err = placeholder("title")
+	require.NoError(t, err)
+	assert.Equal(t, template.HTML("<b>Post</b>"), out)
+
+	out, err = p.funcs(funcs, true)["placeholder"].(placeholderFn)("title")
+	require.NoError(t, err)
+	assert.Equal(t, template.HTML(marker("placeholder:title")+placeholderProbe), out)
+
+	p.reset()
+	assert.Equal(t, "", p.value("title"))
+
+	assert.Empty(t, boundPlaceholderFuncs(template.FuncMap{"placeholder": strings.ToUpper}))
+}
+
+func TestTheme_Placeholders(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", `<title>{{placeholder "title" "Blog"}}</title>`+
+		`<meta name="description" content="{{placeholder "description"}}">`+
+		`<h1>{{placeholder "title"}}</h1>{{template "content" .}}`)
+	store.Add("test", "post", `<!-- layouts/base -->{{define "content"}}{{set_placeholder "title" .Title}}{{set_placeholder "description" "A & B"}}<p>{{.Body}}</p>{{end}}`)
+	store.Add("test", "index", `<!-- layouts/base -->{{define "content"}}<p>index</p>{{end}}`)
+
+	theme := NewTheme("test", store)
+
+	var buf bytes.Buffer
+	require.NoError(t, theme.Write(context.Background(), &buf, "post", map[string]any{"Title": `"Hello" <World>`, "Body": "body"}))
+	assert.Equal(t, `<title>&#34;Hello&#34; &lt;World&gt;</title>`+
+		`<meta name="description" content="A &amp; B">`+
+		`<h1>&#34;Hello&#34; &lt;World&gt;</h1><p>body</p>`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, theme.Write(context.Background(), &buf, "index", nil))
+	assert.Equal(t, `<title>Blog</title><meta name="description" content=""><h1>Blog</h1><p>index</p>`, buf.String())
+}
+
+func TestTheme_Placeholders_Contexts(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{"text", `<p>{{placeholder "p"}}</p>`, "<b>", "<p>&lt;b&gt;</p>", false},
+		{"trusted text", `<p>{{placeholder "p"}}</p>`, template.HTML("<b>"), "<p><b></p>", false},
+		{"rcdata", `<title>{{placeholder "p"}}</title>`, template.HTML("<b>"), "<title>&lt;b&gt;</title>", false},
+		{"quoted attribute", `<p title="{{placeholder "p"}}">`, template.HTML(`" onclick="x()`), `<p title="&#34; onclick=&#34;x()">`, false},
+		{"unquoted attribute", `<p title={{placeholder "p"}}>`, "a onclick=x()", `<p title=a&#32;onclick&#61;x()>`, false},
+		{"url attribute", `<a href="{{placeholder "p"}}">`, "javascript:alert(1)", "", true},
+		{"url query", `<a href="/search?q={{placeholder "p"}}">`, "a&b", "", true},
+		{"event handler", `<a onclick="go({{placeholder "p"}})">`, "1)", "", true},
+		{"script", `<script>var p = {{placeholder "p"}};</script>`, "alert(1)", "", true},
+		{"script string", `<script>var p = "{{placeholder "p"}}";</script>`, `"+alert(1)+"`, "", true},
+		{"style", `<style>p { font-family: "{{placeholder "p"}}" }</style>`, "x", "", true},
+		{"style value", `<style>p { color: {{placeholder "p"}} }</style>`, "red", "<style>p { color: ZgotmplZ }</style>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStoreMemory()
+			store.Add("test", "page", tt.content+`{{set_placeholder "p" .}}`)
+
+			var buf bytes.Buffer
+			err := NewTheme("test", store).Write(context.Background(), &buf, "page", tt.value)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errPlaceholderContext)
+				assert.NotContains(t, buf.String(), "alert")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTheme_Compiled_Placeholders(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{set_placeholder "u" .}}<a href="{{placeholder "u"}}">{{placeholder "u"}}</a>`)
+
+	tpl, err := NewTheme("test", store).Compiled(context.Background(), "page")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, tpl.Execute(&buf, "javascript:alert(1)"))
+	assert.Equal(t, `<a href="#ZgotmplZ">javascript:alert(1)</a>`, buf.String())
+}
@@ -0,0 +1,53 @@
+package got
+
+import "context"
+
+// PostProcessor transforms the output of every render of a theme, e.g. to
+// minify it. Pages are rendered into a buffer when the theme has post
+// processors.
+type PostProcessor interface {
+	Process(ctx context.Context, name string, out []byte) ([]byte, error)
+}
+
+// PostProcessorFunc is an adapter to use ordinary functions as PostProcessor.
+type PostProcessorFunc func(ctx context.Context, name string, out []byte) ([]byte, error)
+
+func (f PostProcessorFunc) Process(ctx context.Context, name string, out []byte) ([]byte, error) {
+	return f(ctx, name, out)
+}
+
+// PostProcessors returns the post processors of the theme.
+func (t *Theme) PostProcessors() []PostProcessor {
+	if processors := t.processors.Load(); processors != nil {
+		return *processors
+	}
+	return nil
+}
+
+// AddPostProcessor registers processors applied, in order, to the output of
+// every render of the theme, before it is written.
+func (t *Theme) AddPostProcessor(processors ...PostProcessor) {
+	for {
+		old := t.processors.Load()
+
+		var list []PostProcessor
+		if old != nil {
+			list = append(list, *old...)
+		}
+		list = append(list, processors...)
+
+		if t.processors.CompareAndSwap(old, &list) {
+			return
+		}
+	}
+}
+
+func (t *Theme) process(ctx context.Context, name string, out []byte) ([]byte, error) {
+	var err error
+	for _, processor := range t.PostProcessors() {
+		if out, err = processor.Process(ctx, name, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,52 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme_PostProcessors(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{.}}</p>  <script>alert(1)</script>`)
+
+	theme := NewTheme("test", store)
+	assert.Empty(t, theme.PostProcessors())
+
+	var names []string
+	theme.AddPostProcessor(
+		PostProcessorFunc(func(_ context.Context, name string, out []byte) ([]byte, error) {
+			names = append(names, name)
+			return bytes.ReplaceAll(out, []byte("  "), nil), nil
+		}),
+		PostProcessorFunc(func(_ context.Context, _ string, out []byte) ([]byte, error) {
+			return bytes.ToUpper(out), nil
+		}),
+	)
+	assert.Len(t, theme.PostProcessors(), 2)
+
+	theme.SetCSPHashes(true)
+
+	var buf bytes.Buffer
+	result, err := theme.Render(context.Background(), &buf, "page", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, `<P>HI</P><SCRIPT>ALERT(1)</SCRIPT>`, buf.String())
+	assert.Equal(t, []string{"page"}, names)
+	assert.Equal(t, []string{cspHash("ALERT(1)")}, result.ScriptHashes, "hashes are computed on the processed output")
+
+	t.Run("error", func(t *testing.T) {
+		errProcess := errors.New("process failed")
+		theme.AddPostProcessor(PostProcessorFunc(func(context.Context, string, []byte) ([]byte, error) {
+			return nil, errProcess
+		}))
+
+		buf.Reset()
+		err := theme.Write(context.Background(), &buf, "page", "hi")
+		assert.ErrorIs(t, err, errProcess)
+		assert.Empty(t, buf.String())
+	})
+}
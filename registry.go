@@ -0,0 +1,60 @@
+package got
+
+import (
+	"slices"
+	"sync"
+)
+
+// ThemeRegistry is a set of themes addressed by name.
+//
+// Themes can reference their parent by name through a registry, see
+// Theme.SetParentName, so registering a new theme under an existing name
+// hot-swaps the parent of every child without rewiring them.
+type ThemeRegistry struct {
+	mu     sync.RWMutex
+	themes map[string]*Theme
+}
+
+func NewThemeRegistry(themes ...*Theme) *ThemeRegistry {
+	r := &ThemeRegistry{themes: make(map[string]*Theme, len(themes))}
+	for _, theme := range themes {
+		r.themes[theme.Name()] = theme
+	}
+	return r
+}
+
+// Register adds the theme, replacing any theme registered under its name.
+func (r *ThemeRegistry) Register(theme *Theme) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.themes[theme.Name()] = theme
+}
+
+func (r *ThemeRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.themes, name)
+}
+
+func (r *ThemeRegistry) Get(name string) (*Theme, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	theme, ok := r.themes[name]
+	return theme, ok
+}
+
+// Names returns the sorted names of the registered themes.
+func (r *ThemeRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.themes))
+	for name := range r.themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package got
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThemeRegistry(t *testing.T) {
+	a := NewTheme("a", NewStoreMemory())
+	b := NewTheme("b", NewStoreMemory())
+
+	registry := NewThemeRegistry(a)
+	registry.Register(b)
+	assert.Equal(t, []string{"a", "b"}, registry.Names())
+
+	theme, ok := registry.Get("a")
+	assert.True(t, ok)
+	assert.Same(t, a, theme)
+
+	other := NewTheme("a", NewStoreMemory())
+	registry.Register(other)
+	theme, _ = registry.Get("a")
+	assert.Same(t, other, theme)
+
+	registry.Unregister("a")
+	_, ok = registry.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"b"}, registry.Names())
+}
+
+func TestTheme_SetParentName(t *testing.T) {
+	v1 := NewStoreMemory()
+	v1.Add("base", "layout", `v1:{{block "content" .}}{{end}}`)
+	v2 := NewStoreMemory()
+	v2.Add("base", "layout", `v2:{{block "content" .}}{{end}}`)
+
+	store := NewStoreMemory()
+	store.Add("child", "page", `<!-- layout -->{{define "content"}}{{.}}{{end}}`)
+
+	registry := NewThemeRegistry()
+	child := NewTheme("child", store)
+	child.SetParentName(registry, "base")
+	assert.Nil(t, child.Parent())
+
+	ctx := context.Background()
+	err := child.Write(ctx, &strings.Builder{}, "page", "x")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	registry.Register(NewTheme("base", v1))
+
+	var buf strings.Builder
+	require.NoError(t, child.Write(ctx, &buf, "page", "x"))
+	assert.Equal(t, "v1:x", buf.String())
+
+	registry.Register(NewTheme("base", v2))
+
+	buf.Reset()
+	require.NoError(t, child.Write(ctx, &buf, "page", "x"))
+	assert.Equal(t, "v2:x", buf.String(), "swapped parent should invalidate the cache")
+
+	parent := NewTheme("direct", store)
+	child.SetParent(parent)
+	assert.Same(t, parent, child.Parent())
+}
@@ -0,0 +1,224 @@
+package got
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"html/template"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"text/template/parse"
+)
+
+// markerPrefix starts the markers left in the output for the values that
+// are only known once the render completes. The random part keeps template
+// content from forging them. Markers are made of letters, digits and
+// underscores only, so the escaping of any context leaves them intact. The
+// markers of placeholders are followed by a probe, see placeholderProbe.
+var markerPrefix = "GOT_" + rand.Text()[:16] + "_"
+
+func marker(key string) string {
+	return markerPrefix + hex.EncodeToString([]byte(key)) + "_"
+}
+
+// RenderResult describes a rendered page.
+type RenderResult struct {
+	// ScriptHashes and StyleHashes are the CSP hash sources, such as
+	// 'sha256-...', of the inline <script> and <style> blocks of the page,
+	// collected when the theme has CSPHashes enabled.
+	ScriptHashes []string
+	StyleHashes  []string
+
+	// Tags are the tags the page was rendered with by cache_tag, see
+	// Theme.InvalidateTag.
+	Tags []string
+}
+
+// renderState is the state of a single render, shared by the functions
+// bound to an instance.
+type renderState struct {
+	// limiter is nil when no Limits are set.
+	limiter      *limiter
+	assets       assets
+	placeholders placeholders
+	tags         tags
+}
+
+func (s *renderState) reset() {
+	if s.limiter != nil {
+		s.limiter.reset()
+	}
+	s.assets.reset()
+	s.placeholders.reset()
+	s.tags.reset()
+}
+
+// fill writes out to w, replacing the markers with their final values.
+func (s *renderState) fill(w io.Writer, out []byte) error {
+	prefix := []byte(markerPrefix)
+	for {
+		i := bytes.Index(out, prefix)
+		if i < 0 {
+			break
+		}
+
+		rest := out[i+len(prefix):]
+		j := bytes.IndexByte(rest, '_')
+		if j < 0 {
+			break
+		}
+
+		key, err := hex.DecodeString(string(rest[:j]))
+		if err != nil {
+			// not a marker, keep it
+			if _, err = w.Write(out[:i+len(prefix)]); err != nil {
+				return err
+			}
+			out = rest
+			continue
+		}
+
+		if _, err = w.Write(out[:i]); err != nil {
+			return err
+		}
+		out = rest[j+1:]
+
+		var value string
+		if name, ok := strings.CutPrefix(string(key), "placeholder:"); ok {
+			var n int
+			if value, n, err = s.placeholders.fill(name, out); err != nil {
+				return err
+			}
+			out = out[n:]
+		} else {
+			value = s.assets.value(string(key))
+		}
+		if _, err = io.WriteString(w, value); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(out)
+	return err
+}
+
+// instance is a clone of a compiled template set bound to its own render
+// state. Instances are pooled and used by one render at a time.
+type instance struct {
+	tpl   *template.Template
+	state *renderState
+}
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+func (c *compiled) newInstance() (*instance, error) {
+	tpl, err := c.assemble()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &renderState{}
+	if c.limits.enabled() {
+		state.limiter = &limiter{limits: c.limits}
+		funcs := state.limiter.funcs(c.funcs)
+		maps.Copy(funcs, c.aliases(funcs))
+		tpl.Funcs(funcs)
+	}
+	if c.buffered {
+		tpl.Funcs(state.assets.funcs(c.funcs, true))
+		tpl.Funcs(state.placeholders.funcs(c.funcs, true))
+	}
+	if c.tagged {
+		tpl.Funcs(state.tags.funcs(c.funcs))
+	}
+
+	return &instance{tpl: tpl, state: state}, nil
+}
+
+// execute renders the set into w, describing the render in result.
+func (c *compiled) execute(w io.Writer, data any, result *RenderResult) error {
+	if c.instances == nil {
+		return c.tpl.Execute(w, data)
+	}
+
+	inst, ok := c.instances.Get().(*instance)
+	if !ok {
+		var err error
+		if inst, err = c.newInstance(); err != nil {
+			return err
+		}
+	}
+	defer c.instances.Put(inst)
+
+	inst.state.reset()
+	defer func() {
+		if len(inst.state.tags) > 0 {
+			result.Tags = slices.Clone(inst.state.tags)
+		}
+	}()
+
+	if !c.buffered {
+		return inst.tpl.Execute(w, data)
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufferPool.Put(buf)
+	}()
+
+	if err := inst.tpl.Execute(buf, data); err != nil {
+		return err
+	}
+	return inst.state.fill(w, buf.Bytes())
+}
+
+// walkIdentifiers calls fn with the name of every function called under
+// node.
+func walkIdentifiers(node parse.Node, fn func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			walkIdentifiers(item, fn)
+		}
+	case *parse.ActionNode:
+		walkIdentifiers(n.Pipe, fn)
+	case *parse.IfNode:
+		walkIdentifiers(n.Pipe, fn)
+		walkIdentifiers(n.List, fn)
+		walkIdentifiers(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkIdentifiers(n.Pipe, fn)
+		walkIdentifiers(n.List, fn)
+		walkIdentifiers(n.ElseList, fn)
+	case *parse.WithNode:
+		walkIdentifiers(n.Pipe, fn)
+		walkIdentifiers(n.List, fn)
+		walkIdentifiers(n.ElseList, fn)
+	case *parse.TemplateNode:
+		walkIdentifiers(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkIdentifiers(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkIdentifiers(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkIdentifiers(n.Node, fn)
+	case *parse.IdentifierNode:
+		fn(n.Ident)
+	}
+}
@@ -0,0 +1,65 @@
+package got
+
+import (
+	"bytes"
+	"testing"
+	"text/template/parse"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderState_Fill(t *testing.T) {
+	state := &renderState{}
+	state.assets.scripts = state.assets.push(nil, "/app.js", nil)
+
+	tests := []struct {
+		name string
+		out  string
+		want string
+	}{
+		{
+			name: "no markers",
+			out:  "<p>plain</p>",
+			want: "<p>plain</p>",
+		},
+		{
+			name: "markers",
+			out:  "a" + marker("scripts") + "b" + marker("unknown") + "c",
+			want: "a<script src=\"/app.js\"></script>\nbc",
+		},
+		{
+			name: "forged marker",
+			out:  "a" + markerPrefix + "zz_b" + marker("scripts"),
+			want: "a" + markerPrefix + "zz_b<script src=\"/app.js\"></script>\n",
+		},
+		{
+			name: "unterminated marker",
+			out:  "a" + markerPrefix,
+			want: "a" + markerPrefix,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, state.fill(&buf, []byte(tt.out)))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestDependency_Calls(t *testing.T) {
+	trees, err := parse.Parse("test", `{{define "a"}}{{if .X}}{{with (lower (push_script "x"))}}{{end}}{{end}}{{end}}{{template "b" (upper "y")}}`, "", "", map[string]any{
+		"lower": true, "upper": true, "push_script": true,
+	})
+	require.NoError(t, err)
+
+	dep := &dependency{parsed: newParsed(trees)}
+	assert.Equal(t, []string{"lower", "push_script", "upper"}, dep.called)
+
+	assert.True(t, dep.calls("push_script"))
+	assert.True(t, dep.calls("missing", "upper"))
+	assert.False(t, dep.calls("scripts"))
+	assert.False(t, dep.calls())
+}
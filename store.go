@@ -7,6 +7,11 @@ import (
 
 var ErrTemplateNotFound = errors.New("template not found")
 
+// ErrNotLister is returned when templates must be listed from a store that
+// doesn't implement Lister, such as in eager mode or for auto include
+// patterns.
+var ErrNotLister = errors.New("store doesn't list templates")
+
 // Store is an interface for loading templates from a store.
 type Store interface {
 	// Find returns a template by its theme and name.
@@ -14,3 +19,60 @@ type Store interface {
 	// If the template is not found, it returns ErrTemplateNotFound.
 	Find(ctx context.Context, theme, name string) (Template, error)
 }
+
+// Lister is implemented by stores that can enumerate the templates of a
+// theme.
+type Lister interface {
+	// List returns the names of all templates of the theme.
+	List(ctx context.Context, theme string) ([]string, error)
+}
+
+// BatchFinder is implemented by stores fetching several templates in a
+// single round trip, such as databases or remote services. Themes use it to
+// fetch a page along with its layouts and partials.
+type BatchFinder interface {
+	// FindMany returns the templates of the theme found among names, keyed
+	// by name. Missing templates are not an error, they are left out.
+	FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error)
+}
+
+// findMany finds the templates of the theme among names with FindMany when
+// the store implements BatchFinder, one by one otherwise. Templates missing
+// because a store is unavailable, see ErrCircuitOpen, are an error.
+func findMany(ctx context.Context, store Store, theme string, names []string) (map[string]Template, error) {
+	if batcher, ok := store.(BatchFinder); ok {
+		return batcher.FindMany(ctx, theme, names)
+	}
+
+	items := make(map[string]Template, len(names))
+	for _, name := range names {
+		item, err := store.Find(ctx, theme, name)
+		if err != nil {
+			if errors.Is(err, ErrTemplateNotFound) && !errors.Is(err, ErrCircuitOpen) {
+				continue
+			}
+			return nil, err
+		}
+		items[name] = item
+	}
+	return items, nil
+}
+
+// Pinger is implemented by stores backed by a remote service or database,
+// to check that it is reachable, see Theme.Health.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// StoreWriter is implemented by stores that accept templates at runtime.
+type StoreWriter interface {
+	Store
+
+	// Save adds the template, replacing the template of the same theme and
+	// name if any.
+	Save(ctx context.Context, item Template) error
+
+	// Delete removes a template of the theme, a missing template is not an
+	// error.
+	Delete(ctx context.Context, theme, name string) error
+}
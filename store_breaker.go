@@ -0,0 +1,256 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	_ Store       = (*StoreBreaker)(nil)
+	_ Lister      = (*StoreBreaker)(nil)
+	_ Pinger      = (*StoreBreaker)(nil)
+	_ BatchFinder = (*StoreBreaker)(nil)
+)
+
+// ErrCircuitOpen is returned, along with ErrTemplateNotFound, for templates
+// requested while the circuit of a StoreBreaker is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerState is the state of the circuit of a StoreBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed passes requests to the store.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen short-circuits requests.
+	BreakerOpen
+	// BreakerHalfOpen passes a single trial request to the store, closing
+	// the circuit if it succeeds.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerOptions configure a StoreBreaker.
+type BreakerOptions struct {
+	// Threshold is the number of consecutive failures opening the circuit,
+	// 5 if zero.
+	Threshold int
+
+	// Cooldown is how long the circuit stays open before a trial request,
+	// 30 seconds if zero.
+	Cooldown time.Duration
+
+	// Fallback, if set, serves the requests while the circuit is open.
+	Fallback Store
+
+	// OnStateChange, if set, is called when the circuit changes state.
+	// Pages built while the circuit was open may lack templates of the
+	// store, clearing the themes once it closes rebuilds them:
+	//
+	//	OnStateChange: func(_, to got.BreakerState) {
+	//		if to == got.BreakerClosed {
+	//			theme.Clear()
+	//		}
+	//	}
+	OnStateChange func(from, to BreakerState)
+}
+
+// StoreBreaker is a store wrapper that stops calling a failing store, such as
+// a flaky remote template service, for a while, so it doesn't add latency to
+// every render.
+//
+// After Threshold consecutive failures, requests are short-circuited to the
+// fallback store, or fail with ErrTemplateNotFound so the parent themes
+// provide the templates. ErrTemplateNotFound is not a failure, and
+// canceled requests count for nothing: once canceled, the trial request of a
+// half-open circuit is passed on to the next request.
+type StoreBreaker struct {
+	store   Store
+	options BreakerOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func NewStoreBreaker(store Store, options BreakerOptions) *StoreBreaker {
+	if options.Threshold <= 0 {
+		options.Threshold = 5
+	}
+	if options.Cooldown <= 0 {
+		options.Cooldown = 30 * time.Second
+	}
+	return &StoreBreaker{store: store, options: options, now: time.Now}
+}
+
+// State returns the current state of the circuit.
+func (s *StoreBreaker) State() BreakerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == BreakerOpen && s.now().Sub(s.openedAt) >= s.options.Cooldown {
+		return BreakerHalfOpen
+	}
+	return s.state
+}
+
+func (s *StoreBreaker) Find(ctx context.Context, theme, name string) (Template, error) {
+	trial, ok := s.allow()
+	if !ok {
+		if s.options.Fallback != nil {
+			return s.options.Fallback.Find(ctx, theme, name)
+		}
+		return nil, fmt.Errorf("store breaker: template %s/%s not found: %w: %w", theme, name, ErrCircuitOpen, ErrTemplateNotFound)
+	}
+
+	item, err := s.store.Find(ctx, theme, name)
+	s.done(trial, err)
+	return item, err
+}
+
+// FindMany finds the templates with FindMany when the store implements
+// BatchFinder, one by one otherwise. While the circuit is open, they are
+// found in the fallback store, or fail with ErrCircuitOpen.
+func (s *StoreBreaker) FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error) {
+	trial, ok := s.allow()
+	if !ok {
+		if s.options.Fallback != nil {
+			return findMany(ctx, s.options.Fallback, theme, names)
+		}
+		return nil, fmt.Errorf("store breaker: templates of %s not found: %w: %w", theme, ErrCircuitOpen, ErrTemplateNotFound)
+	}
+
+	items, err := findMany(ctx, s.store, theme, names)
+	s.done(trial, err)
+	return items, err
+}
+
+// List lists the templates of the store, or of the fallback store while the
+// circuit is open. Stores not implementing Lister fail with ErrNotLister.
+func (s *StoreBreaker) List(ctx context.Context, theme string) ([]string, error) {
+	lister, ok := s.store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("store breaker: failed to list templates of %s: %w", theme, ErrNotLister)
+	}
+
+	trial, ok := s.allow()
+	if !ok {
+		if lister, ok := s.options.Fallback.(Lister); ok {
+			return lister.List(ctx, theme)
+		}
+		return nil, fmt.Errorf("store breaker: failed to list templates of %s: %w", theme, ErrCircuitOpen)
+	}
+
+	names, err := lister.List(ctx, theme)
+	s.done(trial, err)
+	return names, err
+}
+
+// Ping reports an open circuit as unhealthy, and pings the store if it
+// implements Pinger.
+func (s *StoreBreaker) Ping(ctx context.Context) error {
+	if s.State() == BreakerOpen {
+		return fmt.Errorf("store breaker: %w", ErrCircuitOpen)
+	}
+	if pinger, ok := s.store.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// allow reports whether a request may be passed to the store, and whether
+// it is the trial request of a half-open circuit. Once the cooldown is
+// over, a single trial request is allowed until it completes.
+func (s *StoreBreaker) allow() (trial, ok bool) {
+	defer s.lock()()
+
+	switch s.state {
+	case BreakerClosed:
+		return false, true
+	case BreakerOpen:
+		if s.now().Sub(s.openedAt) < s.options.Cooldown {
+			return false, false
+		}
+		s.state = BreakerHalfOpen
+	}
+
+	if s.trial {
+		return false, false
+	}
+	s.trial = true
+	return true, true
+}
+
+// done records the outcome of a request allowed by allow. Only the trial
+// request resolves a half-open circuit, the requests passed before the
+// circuit opened don't.
+func (s *StoreBreaker) done(trial bool, err error) {
+	canceled := errors.Is(err, context.Canceled)
+	failed := err != nil && !canceled && !errors.Is(err, ErrTemplateNotFound)
+
+	defer s.lock()()
+
+	if trial {
+		s.trial = false
+		switch {
+		case canceled:
+			// the store wasn't tried, the next request is the trial
+		case failed:
+			s.openedAt = s.now()
+			s.state = BreakerOpen
+		default:
+			s.failures = 0
+			s.state = BreakerClosed
+		}
+		return
+	}
+
+	if canceled || s.state != BreakerClosed {
+		return
+	}
+	if !failed {
+		s.failures = 0
+		return
+	}
+
+	s.failures++
+	if s.failures >= s.options.Threshold {
+		s.openedAt = s.now()
+		s.state = BreakerOpen
+	}
+}
+
+// lock locks the breaker and returns the function unlocking it, which
+// reports a state change once unlocked, so OnStateChange may use the
+// breaker.
+func (s *StoreBreaker) lock() func() {
+	s.mu.Lock()
+	from := s.state
+
+	return func() {
+		to := s.state
+		s.mu.Unlock()
+
+		if from != to && s.options.OnStateChange != nil {
+			s.options.OnStateChange(from, to)
+		}
+	}
+}
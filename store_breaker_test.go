@@ -0,0 +1,344 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStore is a memory store failing with err when set.
+type flakyStore struct {
+	*StoreMemory
+	err   error
+	finds int
+}
+
+func (s *flakyStore) Find(ctx context.Context, theme, name string) (Template, error) {
+	s.finds++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.StoreMemory.Find(ctx, theme, name)
+}
+
+func newTestBreaker(store Store, options BreakerOptions) (*StoreBreaker, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewStoreBreaker(store, options)
+	breaker.now = func() time.Time { return now }
+	return breaker, &now
+}
+
+func TestNewStoreBreaker(t *testing.T) {
+	breaker := NewStoreBreaker(NewStoreMemory(), BreakerOptions{})
+	assert.Equal(t, 5, breaker.options.Threshold)
+	assert.Equal(t, 30*time.Second, breaker.options.Cooldown)
+	assert.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerState_String(t *testing.T) {
+	tests := []struct {
+		state BreakerState
+		want  string
+	}{
+		{BreakerClosed, "closed"},
+		{BreakerOpen, "open"},
+		{BreakerHalfOpen, "half-open"},
+		{BreakerState(9), "BreakerState(9)"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.state.String())
+	}
+}
+
+func TestStoreBreaker_Find(t *testing.T) {
+	ctx := context.Background()
+	outage := errors.New("connection refused")
+
+	store := &flakyStore{StoreMemory: NewStoreMemory()}
+	store.Add("test", "page", "content")
+
+	var changes []string
+	breaker, now := newTestBreaker(store, BreakerOptions{
+		Threshold: 2,
+		Cooldown:  time.Minute,
+		OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	item, err := breaker.Find(ctx, "test", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "content", item.Content())
+
+	store.err = outage
+	for range 2 {
+		_, err = breaker.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, outage)
+	}
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// short-circuited without calling the store
+	_, err = breaker.Find(ctx, "test", "page")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	assert.Equal(t, 3, store.finds)
+
+	// failed trial
+	*now = now.Add(time.Minute)
+	assert.Equal(t, BreakerHalfOpen, breaker.State())
+	_, err = breaker.Find(ctx, "test", "page")
+	assert.ErrorIs(t, err, outage)
+	assert.Equal(t, BreakerOpen, breaker.State())
+	assert.Equal(t, 4, store.finds)
+
+	// successful trial
+	store.err = nil
+	*now = now.Add(time.Minute)
+	item, err = breaker.Find(ctx, "test", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "content", item.Content())
+	assert.Equal(t, BreakerClosed, breaker.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, changes)
+}
+
+func TestStoreBreaker_NotFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"not found", ErrTemplateNotFound},
+		{"canceled", context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &flakyStore{StoreMemory: NewStoreMemory(), err: tt.err}
+			breaker, _ := newTestBreaker(store, BreakerOptions{Threshold: 1})
+
+			for range 3 {
+				_, err := breaker.Find(ctx, "test", "page")
+				assert.ErrorIs(t, err, tt.err)
+			}
+			assert.Equal(t, BreakerClosed, breaker.State())
+			assert.Equal(t, 3, store.finds)
+		})
+	}
+}
+
+func TestStoreBreaker_Trial(t *testing.T) {
+	ctx := context.Background()
+	outage := errors.New("connection refused")
+
+	t.Run("canceled", func(t *testing.T) {
+		store := &flakyStore{StoreMemory: NewStoreMemory(), err: outage}
+		store.Add("test", "page", "content")
+		breaker, now := newTestBreaker(store, BreakerOptions{Threshold: 1, Cooldown: time.Minute})
+
+		_, err := breaker.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, outage)
+		*now = now.Add(time.Minute)
+
+		store.err = context.Canceled
+		_, err = breaker.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, BreakerHalfOpen, breaker.State(), "a canceled trial doesn't close the circuit")
+
+		// the next request is the trial
+		store.err = outage
+		_, err = breaker.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, outage)
+		assert.Equal(t, BreakerOpen, breaker.State())
+		assert.Equal(t, 3, store.finds)
+	})
+
+	t.Run("in flight", func(t *testing.T) {
+		breaker, now := newTestBreaker(NewStoreMemory(), BreakerOptions{Threshold: 1, Cooldown: time.Minute})
+
+		// passed before the circuit opens, completes once it is half-open
+		inflight, ok := breaker.allow()
+		require.True(t, ok)
+		assert.False(t, inflight)
+
+		failing, ok := breaker.allow()
+		require.True(t, ok)
+		breaker.done(failing, outage)
+		assert.Equal(t, BreakerOpen, breaker.State())
+
+		*now = now.Add(time.Minute)
+		trial, ok := breaker.allow()
+		require.True(t, ok)
+		assert.True(t, trial)
+
+		breaker.done(inflight, nil)
+		assert.Equal(t, BreakerHalfOpen, breaker.State(), "only the trial resolves a half-open circuit")
+		_, ok = breaker.allow()
+		assert.False(t, ok, "the trial is still in flight")
+
+		breaker.done(trial, nil)
+		assert.Equal(t, BreakerClosed, breaker.State())
+	})
+}
+
+func TestStoreBreaker_Fallback(t *testing.T) {
+	ctx := context.Background()
+
+	store := &flakyStore{StoreMemory: NewStoreMemory(), err: errors.New("timeout")}
+	fallback := NewStoreMemory()
+	fallback.Add("test", "page", "snapshot")
+
+	breaker, _ := newTestBreaker(store, BreakerOptions{Threshold: 1, Fallback: fallback})
+
+	_, err := breaker.Find(ctx, "test", "page")
+	require.Error(t, err)
+
+	item, err := breaker.Find(ctx, "test", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot", item.Content())
+
+	names, err := breaker.List(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"page"}, names)
+}
+
+func TestStoreBreaker_List(t *testing.T) {
+	ctx := context.Background()
+
+	store := NewStoreMemory()
+	store.Add("test", "page", "content")
+
+	names, err := NewStoreBreaker(store, BreakerOptions{}).List(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"page"}, names)
+
+	_, err = NewStoreBreaker(&MockStore{}, BreakerOptions{}).List(ctx, "test")
+	assert.ErrorIs(t, err, ErrNotLister)
+
+	breaker, _ := newTestBreaker(&flakyStore{StoreMemory: store, err: errors.New("timeout")}, BreakerOptions{Threshold: 1})
+	_, _ = breaker.Find(ctx, "test", "page")
+	_, err = breaker.List(ctx, "test")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
+
+func TestStoreBreaker_Ping(t *testing.T) {
+	ctx := context.Background()
+
+	store := &pingStore{StoreMemory: NewStoreMemory()}
+	breaker, now := newTestBreaker(&flakyStore{StoreMemory: NewStoreMemory(), err: errors.New("timeout")}, BreakerOptions{Threshold: 1, Cooldown: time.Minute})
+	assert.NoError(t, breaker.Ping(ctx))
+
+	_, _ = breaker.Find(ctx, "test", "page")
+	assert.ErrorIs(t, breaker.Ping(ctx), ErrCircuitOpen)
+
+	*now = now.Add(time.Minute)
+	assert.NoError(t, breaker.Ping(ctx))
+
+	store.err = errors.New("down")
+	assert.ErrorIs(t, NewStoreBreaker(store, BreakerOptions{}).Ping(ctx), store.err)
+}
+
+func TestStoreBreaker_OnStateChange(t *testing.T) {
+	var breaker *StoreBreaker
+	var states []BreakerState
+
+	breaker = NewStoreBreaker(&flakyStore{StoreMemory: NewStoreMemory(), err: errors.New("timeout")}, BreakerOptions{
+		Threshold: 1,
+		OnStateChange: func(_, _ BreakerState) {
+			// called unlocked
+			states = append(states, breaker.State())
+		},
+	})
+
+	_, _ = breaker.Find(context.Background(), "test", "page")
+	assert.Equal(t, []BreakerState{BreakerOpen}, states)
+}
+
+func TestTheme_StoreBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	parentStore := NewStoreMemory()
+	parentStore.Add("base", "page", "parent")
+
+	childStore := &flakyStore{StoreMemory: NewStoreMemory()}
+	childStore.Add("child", "page", "child")
+
+	// a failed render fails the batch request and the template request
+	breaker, now := newTestBreaker(childStore, BreakerOptions{Threshold: 2, Cooldown: time.Minute})
+
+	parent := NewTheme("base", parentStore)
+	child := NewTheme("child", breaker)
+	child.SetParent(parent)
+
+	render := func() string {
+		var b strings.Builder
+		_, err := child.Render(ctx, &b, "page", nil)
+		require.NoError(t, err)
+		return b.String()
+	}
+
+	childStore.err = errors.New("timeout")
+	_, err := child.Render(ctx, &strings.Builder{}, "page", nil)
+	require.Error(t, err)
+
+	// the parent serves the page during the outage
+	assert.Equal(t, "parent", render())
+
+	childStore.err = nil
+	*now = now.Add(time.Minute)
+	child.Clear()
+
+	// the parent isn't remembered as the owner
+	assert.Equal(t, "child", render())
+}
+
+func TestStoreBreaker_FindMany(t *testing.T) {
+	ctx := context.Background()
+
+	store := &flakyStore{StoreMemory: NewStoreMemory()}
+	store.Add("test", "a", "content a")
+	fallback := NewStoreMemory()
+	fallback.Add("test", "a", "snapshot a")
+
+	breaker, _ := newTestBreaker(store, BreakerOptions{Threshold: 1})
+	items, err := breaker.FindMany(ctx, "test", []string{"a", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "content a", items["a"].Content())
+
+	store.err = errors.New("timeout")
+	_, err = breaker.FindMany(ctx, "test", []string{"a"})
+	assert.ErrorIs(t, err, store.err)
+	assert.Equal(t, BreakerOpen, breaker.State())
+
+	// short-circuited without calling the store
+	_, err = breaker.FindMany(ctx, "test", []string{"a"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 3, store.finds)
+
+	breaker, _ = newTestBreaker(store, BreakerOptions{Threshold: 1, Fallback: fallback})
+	_, _ = breaker.Find(ctx, "test", "a")
+	items, err = breaker.FindMany(ctx, "test", []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot a", items["a"].Content())
+
+	// templates of a short-circuited store aren't missing from a chain
+	open := &MockStore{}
+	open.On("Find", ctx, "test", "b").Return(nil, fmt.Errorf("%w: %w", ErrCircuitOpen, ErrTemplateNotFound)).Once()
+	_, err = NewStoreChain(fallback, open).FindMany(ctx, "test", []string{"b"})
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 )
 
-var _ Store = (*StoreChain)(nil)
+var (
+	_ Store       = (*StoreChain)(nil)
+	_ Lister      = (*StoreChain)(nil)
+	_ Pinger      = (*StoreChain)(nil)
+	_ BatchFinder = (*StoreChain)(nil)
+)
 
 // StoreChain is a store implementation that chains multiple stores together.
 type StoreChain struct {
@@ -34,3 +41,62 @@ func (s *StoreChain) Find(ctx context.Context, theme, name string) (Template, er
 
 	return nil, fmt.Errorf("store chain: template %s/%s not found: %w", theme, name, ErrTemplateNotFound)
 }
+
+// FindMany finds the templates in the chained stores in turn, each one
+// being asked for the templates not found yet, with FindMany when it
+// implements BatchFinder.
+func (s *StoreChain) FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error) {
+	items := make(map[string]Template, len(names))
+	pending := names
+	for _, store := range s.stores {
+		if len(pending) == 0 {
+			break
+		}
+
+		found, err := findMany(ctx, store, theme, pending)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(items, found)
+		pending = slices.DeleteFunc(slices.Clone(pending), func(name string) bool {
+			_, ok := found[name]
+			return ok
+		})
+	}
+	return items, nil
+}
+
+// List returns the names of the templates of the theme in all chained stores
+// implementing Lister.
+func (s *StoreChain) List(ctx context.Context, theme string) ([]string, error) {
+	var names []string
+	for _, store := range s.stores {
+		lister, ok := store.(Lister)
+		if !ok {
+			continue
+		}
+
+		items, err := lister.List(ctx, theme)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, items...)
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// Ping pings all chained stores implementing Pinger and returns their joined
+// errors.
+func (s *StoreChain) Ping(ctx context.Context) error {
+	var errs []error
+	for _, store := range s.stores {
+		if pinger, ok := store.(Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
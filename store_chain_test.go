@@ -367,3 +367,76 @@ func TestStoreChain_StoreInterface(t *testing.T) {
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, ErrTemplateNotFound)
 }
+
+// MockLister is a mock store implementing the Lister interface
+type MockLister struct {
+	MockStore
+}
+
+func (m *MockLister) List(ctx context.Context, theme string) ([]string, error) {
+	args := m.Called(ctx, theme)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func TestStoreChain_List(t *testing.T) {
+	ctx := context.Background()
+
+	memory := NewStoreMemory()
+	memory.Add("test", "b", "b")
+	memory.Add("test", "a", "a")
+
+	lister := &MockLister{}
+	lister.On("List", ctx, "test").Return([]string{"c", "a"}, nil).Once()
+
+	chain := NewStoreChain(memory, &MockStore{}, lister)
+
+	names, err := chain.List(ctx, "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	lister.On("List", ctx, "test").Return(nil, errors.New("list failed")).Once()
+
+	_, err = chain.List(ctx, "test")
+	assert.EqualError(t, err, "list failed")
+
+	lister.AssertExpectations(t)
+}
+
+func TestStoreChain_Ping(t *testing.T) {
+	down := &pingStore{StoreMemory: NewStoreMemory(), err: errors.New("down")}
+	up := &pingStore{StoreMemory: NewStoreMemory()}
+
+	chain := NewStoreChain(NewStoreMemory(), up)
+	assert.NoError(t, chain.Ping(context.Background()))
+
+	chain.Add(down)
+	assert.EqualError(t, chain.Ping(context.Background()), "down")
+	assert.Equal(t, 2, up.pings)
+	assert.Equal(t, 1, down.pings)
+}
+
+func TestStoreChain_FindMany(t *testing.T) {
+	ctx := context.Background()
+
+	batch := &batchStore{StoreMemory: NewStoreMemory()}
+	batch.Add("test", "a", "batch a")
+	memory := NewStoreMemory()
+	memory.Add("test", "a", "memory a")
+	memory.Add("test", "b", "memory b")
+
+	items, err := NewStoreChain(batch, memory).FindMany(ctx, "test", []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "batch a", items["a"].Content())
+	assert.Equal(t, "memory b", items["b"].Content())
+	assert.Equal(t, [][]string{{"test", "a", "b", "missing"}}, batch.batches)
+
+	failing := &MockStore{}
+	failing.On("Find", ctx, "test", "b").Return(nil, errors.New("timeout")).Once()
+	_, err = NewStoreChain(batch, failing).FindMany(ctx, "test", []string{"a", "b"})
+	assert.EqualError(t, err, "timeout")
+	failing.AssertExpectations(t)
+}
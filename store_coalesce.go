@@ -0,0 +1,136 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+)
+
+var (
+	_ Store       = (*StoreCoalesce)(nil)
+	_ Lister      = (*StoreCoalesce)(nil)
+	_ Pinger      = (*StoreCoalesce)(nil)
+	_ BatchFinder = (*StoreCoalesce)(nil)
+)
+
+var errCoalescePanic = errors.New("store coalesce: request panicked")
+
+// StoreCoalesce is a store wrapper collapsing concurrent requests for the
+// same template into a single request to the store, so a slow remote store
+// isn't stampeded while the template caches are cold.
+//
+// Callers waiting for a request return when their context is done. A
+// request failed by the context of its caller is made again for the
+// callers waiting for it.
+type StoreCoalesce struct {
+	store   Store
+	finds   storeFlight[Template]
+	batches storeFlight[map[string]Template]
+	lists   storeFlight[[]string]
+}
+
+func NewStoreCoalesce(store Store) *StoreCoalesce {
+	return &StoreCoalesce{store: store}
+}
+
+func (s *StoreCoalesce) Find(ctx context.Context, theme, name string) (Template, error) {
+	return s.finds.do(ctx, theme+"\x00"+name, func() (Template, error) {
+		return s.store.Find(ctx, theme, name)
+	})
+}
+
+// FindMany finds the templates with FindMany when the store implements
+// BatchFinder, one by one otherwise, coalescing concurrent requests for the
+// same names.
+func (s *StoreCoalesce) FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error) {
+	key := theme + "\x00" + strings.Join(names, "\x00")
+	items, err := s.batches.do(ctx, key, func() (map[string]Template, error) {
+		return findMany(ctx, s.store, theme, names)
+	})
+	// callers may modify the shared map
+	return maps.Clone(items), err
+}
+
+// List lists the templates of the store, coalescing concurrent requests.
+// Stores not implementing Lister fail with ErrNotLister.
+func (s *StoreCoalesce) List(ctx context.Context, theme string) ([]string, error) {
+	lister, ok := s.store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("store coalesce: failed to list templates of %s: %w", theme, ErrNotLister)
+	}
+
+	names, err := s.lists.do(ctx, theme, func() ([]string, error) {
+		return lister.List(ctx, theme)
+	})
+	// callers may modify the shared list
+	return append([]string(nil), names...), err
+}
+
+func (s *StoreCoalesce) Ping(ctx context.Context) error {
+	if pinger, ok := s.store.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// storeFlight coalesces concurrent store requests.
+type storeFlight[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*cacheCall[V]
+}
+
+func (f *storeFlight[V]) do(ctx context.Context, key string, fn func() (V, error)) (V, error) {
+	for {
+		call, started := f.start(key)
+		if started {
+			f.run(key, call, fn)
+			return call.value, call.err
+		}
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+
+		// the request was canceled for its caller, not for this one
+		if isContextErr(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.value, call.err
+	}
+}
+
+func (f *storeFlight[V]) start(key string) (*cacheCall[V], bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if call, ok := f.calls[key]; ok {
+		return call, false
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]*cacheCall[V])
+	}
+	call := &cacheCall[V]{done: make(chan struct{}), err: errCoalescePanic}
+	f.calls[key] = call
+	return call, true
+}
+
+func (f *storeFlight[V]) run(key string, call *cacheCall[V], fn func() (V, error)) {
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fn()
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,189 @@
+package got
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowStore is a memory store blocking its requests until release is closed.
+type slowStore struct {
+	*StoreMemory
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (s *slowStore) Find(ctx context.Context, theme, name string) (Template, error) {
+	s.calls.Add(1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.StoreMemory.Find(ctx, theme, name)
+}
+
+func (s *slowStore) List(ctx context.Context, theme string) ([]string, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.StoreMemory.List(ctx, theme)
+}
+
+// waitCalls waits for the store to have n requests in flight.
+func (s *slowStore) waitCalls(t *testing.T, n int32) {
+	t.Helper()
+	require.Eventually(t, func() bool { return s.calls.Load() == n }, time.Second, time.Millisecond)
+}
+
+func TestStoreCoalesce_Find(t *testing.T) {
+	store := &slowStore{StoreMemory: NewStoreMemory(), release: make(chan struct{})}
+	store.Add("test", "a", "content a")
+	store.Add("test", "b", "content b")
+
+	coalesce := NewStoreCoalesce(store)
+
+	var (
+		wg       sync.WaitGroup
+		contents [10]string
+	)
+	for i := range contents {
+		wg.Go(func() {
+			name := "a"
+			if i%2 == 1 {
+				name = "b"
+			}
+			item, err := coalesce.Find(context.Background(), "test", name)
+			assert.NoError(t, err)
+			if err == nil {
+				contents[i] = item.Content()
+			}
+		})
+	}
+
+	store.waitCalls(t, 2)
+	// let the callers join the requests in flight
+	time.Sleep(10 * time.Millisecond)
+	close(store.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(2), store.calls.Load())
+	for i, content := range contents {
+		if i%2 == 0 {
+			assert.Equal(t, "content a", content)
+		} else {
+			assert.Equal(t, "content b", content)
+		}
+	}
+
+	// completed requests aren't reused
+	_, err := coalesce.Find(context.Background(), "test", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), store.calls.Load())
+}
+
+func TestStoreCoalesce_NotFound(t *testing.T) {
+	_, err := NewStoreCoalesce(NewStoreMemory()).Find(context.Background(), "test", "missing")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestStoreCoalesce_Context(t *testing.T) {
+	t.Run("waiter canceled", func(t *testing.T) {
+		store := &slowStore{StoreMemory: NewStoreMemory(), release: make(chan struct{})}
+		store.Add("test", "page", "content")
+		coalesce := NewStoreCoalesce(store)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := coalesce.Find(context.Background(), "test", "page")
+			assert.NoError(t, err)
+		}()
+		store.waitCalls(t, 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := coalesce.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, context.Canceled)
+
+		close(store.release)
+		<-done
+	})
+
+	t.Run("leader canceled", func(t *testing.T) {
+		store := &slowStore{StoreMemory: NewStoreMemory(), release: make(chan struct{})}
+		store.Add("test", "page", "content")
+		coalesce := NewStoreCoalesce(store)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		leader := make(chan error)
+		go func() {
+			_, err := coalesce.Find(ctx, "test", "page")
+			leader <- err
+		}()
+		store.waitCalls(t, 1)
+
+		waiter := make(chan Template)
+		go func() {
+			item, err := coalesce.Find(context.Background(), "test", "page")
+			assert.NoError(t, err)
+			waiter <- item
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		cancel()
+		assert.ErrorIs(t, <-leader, context.Canceled)
+
+		// the waiter requests the template again
+		store.waitCalls(t, 2)
+		close(store.release)
+		item := <-waiter
+		require.NotNil(t, item)
+		assert.Equal(t, "content", item.Content())
+	})
+}
+
+func TestStoreCoalesce_List(t *testing.T) {
+	store := &slowStore{StoreMemory: NewStoreMemory(), release: make(chan struct{})}
+	store.Add("test", "page", "content")
+	close(store.release)
+
+	coalesce := NewStoreCoalesce(store)
+	names, err := coalesce.List(context.Background(), "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"page"}, names)
+
+	_, err = NewStoreCoalesce(&MockStore{}).List(context.Background(), "test")
+	assert.ErrorIs(t, err, ErrNotLister)
+}
+
+func TestStoreCoalesce_Ping(t *testing.T) {
+	store := &pingStore{StoreMemory: NewStoreMemory()}
+	require.NoError(t, NewStoreCoalesce(store).Ping(context.Background()))
+	assert.Equal(t, 1, store.pings)
+
+	assert.NoError(t, NewStoreCoalesce(NewStoreMemory()).Ping(context.Background()))
+}
+
+func TestStoreCoalesce_FindMany(t *testing.T) {
+	store := &batchStore{StoreMemory: NewStoreMemory()}
+	store.Add("test", "a", "content a")
+
+	coalesce := NewStoreCoalesce(store)
+	items, err := coalesce.FindMany(context.Background(), "test", []string{"a", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "content a", items["a"].Content())
+	assert.Equal(t, [][]string{{"test", "a", "missing"}}, store.batches)
+
+	// one by one for stores not implementing BatchFinder
+	memory := NewStoreMemory()
+	memory.Add("test", "a", "content a")
+	items, err = NewStoreCoalesce(memory).FindMany(context.Background(), "test", []string{"a", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
@@ -5,15 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"sync/atomic"
 
 	"github.com/gowool/got/internal"
 )
 
-var _ Store = (*StoreFS)(nil)
+var (
+	_ Store  = (*StoreFS)(nil)
+	_ Lister = (*StoreFS)(nil)
+)
 
 // StoreFS is a store implementation that loads templates from a filesystem.
 type StoreFS struct {
-	fs fs.FS
+	fs       fs.FS
+	zeroCopy atomic.Bool
+	parser   atomic.Pointer[DirectiveParser]
 }
 
 func NewStoreFS(fsys fs.FS) *StoreFS {
@@ -22,6 +28,36 @@ func NewStoreFS(fsys fs.FS) *StoreFS {
 	}
 }
 
+// ZeroCopy reports whether template content aliases the bytes read from the
+// filesystem instead of copying them.
+func (s *StoreFS) ZeroCopy() bool {
+	return s.zeroCopy.Load()
+}
+
+// SetZeroCopy makes template content alias the bytes read from the
+// filesystem instead of copying them, saving an allocation per template.
+//
+// It is only safe while the filesystem never reuses or mutates the buffers
+// it hands out, which holds for fs.ReadFile over most fs.FS implementations.
+func (s *StoreFS) SetZeroCopy(zeroCopy bool) {
+	s.zeroCopy.Store(zeroCopy)
+}
+
+// DirectiveParser returns the parser extracting the layout directive of
+// templates, CommentDirective by default.
+func (s *StoreFS) DirectiveParser() DirectiveParser {
+	if parser := s.parser.Load(); parser != nil {
+		return *parser
+	}
+	return CommentDirective
+}
+
+// SetDirectiveParser sets the parser extracting the layout directive of
+// templates, e.g. FrontMatterDirective.
+func (s *StoreFS) SetDirectiveParser(parser DirectiveParser) {
+	s.parser.Store(&parser)
+}
+
 func (s *StoreFS) Find(_ context.Context, theme, name string) (Template, error) {
 	fsys, err := fs.Sub(s.fs, theme)
 	if err != nil {
@@ -36,5 +72,42 @@ func (s *StoreFS) Find(_ context.Context, theme, name string) (Template, error)
 		return nil, fmt.Errorf("store fs: failed to read template %s/%s: %w", theme, name, err)
 	}
 
-	return newTemplate(theme, name, internal.String(raw)), nil
+	var content string
+	if s.zeroCopy.Load() {
+		content = internal.String(raw)
+	} else {
+		content = string(raw)
+	}
+
+	item := newTemplateWith(s.DirectiveParser(), theme, name, content)
+	if info, err := fs.Stat(fsys, name); err == nil {
+		item.info.ModTime = info.ModTime()
+	}
+	return item, nil
+}
+
+func (s *StoreFS) List(_ context.Context, theme string) ([]string, error) {
+	fsys, err := fs.Sub(s.fs, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == "." {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("store fs: failed to list templates of %s: %w", theme, err)
+	}
+
+	return names, nil
 }
@@ -381,3 +381,63 @@ func (m *mockFile) Stat() (fs.FileInfo, error) {
 func (m *mockFile) Close() error {
 	return nil
 }
+
+// reusingFS hands out the same buffer on every read, like a store backed by
+// a pooled buffer would.
+type reusingFS struct {
+	buf []byte
+}
+
+func (f *reusingFS) Open(name string) (fs.File, error) {
+	return nil, fs.ErrNotExist
+}
+
+func (f *reusingFS) ReadFile(name string) ([]byte, error) {
+	return f.buf, nil
+}
+
+func (f *reusingFS) Sub(dir string) (fs.FS, error) {
+	return f, nil
+}
+
+func TestStoreFS_ZeroCopy(t *testing.T) {
+	fsys := &reusingFS{buf: []byte("first")}
+	store := NewStoreFS(fsys)
+	ctx := context.Background()
+
+	assert.False(t, store.ZeroCopy())
+
+	tpl, err := store.Find(ctx, "default", "page.html")
+	require.NoError(t, err)
+	copy(fsys.buf, "reuse")
+	assert.Equal(t, "first", tpl.Content(), "content should be copied by default")
+
+	store.SetZeroCopy(true)
+	assert.True(t, store.ZeroCopy())
+
+	tpl, err = store.Find(ctx, "default", "page.html")
+	require.NoError(t, err)
+	copy(fsys.buf, "again")
+	assert.Equal(t, "again", tpl.Content(), "content should alias the buffer in zero-copy mode")
+}
+
+func TestStoreFS_List(t *testing.T) {
+	fsys := fstest.MapFS{
+		"default/home.html":         &fstest.MapFile{Data: []byte("home")},
+		"default/partials/nav.html": &fstest.MapFile{Data: []byte("nav")},
+		"admin/dashboard.html":      &fstest.MapFile{Data: []byte("dashboard")},
+	}
+	store := NewStoreFS(fsys)
+	ctx := context.Background()
+
+	names, err := store.List(ctx, "default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home.html", "partials/nav.html"}, names)
+
+	names, err = store.List(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	_, err = store.List(ctx, "../invalid")
+	assert.Error(t, err)
+}
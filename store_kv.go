@@ -0,0 +1,75 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+var _ Store = (*StoreKV)(nil)
+
+// StoreKV is a store implementation adapting a key-value system, such as
+// memcached, DynamoDB or a custom API, with a single get function:
+//
+//	store := got.NewStoreKV(func(ctx context.Context, key string) ([]byte, error) {
+//		item, err := mc.Get(key)
+//		if errors.Is(err, memcache.ErrCacheMiss) {
+//			return nil, nil
+//		}
+//		if err != nil {
+//			return nil, err
+//		}
+//		return item.Value, nil
+//	}, nil)
+type StoreKV struct {
+	get    func(ctx context.Context, key string) ([]byte, error)
+	key    func(theme, name string) string
+	parser atomic.Pointer[DirectiveParser]
+}
+
+// NewStoreKV returns a store reading templates with get, under the keys
+// returned by key, "theme/name" if nil.
+//
+// Get reports missing keys with a nil value and no error, or with an error
+// wrapping ErrTemplateNotFound.
+func NewStoreKV(get func(ctx context.Context, key string) ([]byte, error), key func(theme, name string) string) *StoreKV {
+	if key == nil {
+		key = func(theme, name string) string {
+			return theme + "/" + name
+		}
+	}
+	return &StoreKV{get: get, key: key}
+}
+
+// DirectiveParser returns the parser extracting the layout directive of
+// templates, CommentDirective by default.
+func (s *StoreKV) DirectiveParser() DirectiveParser {
+	if parser := s.parser.Load(); parser != nil {
+		return *parser
+	}
+	return CommentDirective
+}
+
+// SetDirectiveParser sets the parser extracting the layout directive of
+// templates, e.g. FrontMatterDirective.
+func (s *StoreKV) SetDirectiveParser(parser DirectiveParser) {
+	s.parser.Store(&parser)
+}
+
+func (s *StoreKV) Find(ctx context.Context, theme, name string) (Template, error) {
+	key := s.key(theme, name)
+
+	raw, err := s.get(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			return nil, fmt.Errorf("store kv: template %s/%s not found: %w", theme, name, err)
+		}
+		return nil, fmt.Errorf("store kv: failed to get template %s/%s at %s: %w", theme, name, key, err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("store kv: template %s/%s not found: %w", theme, name, ErrTemplateNotFound)
+	}
+
+	return newTemplateWith(s.DirectiveParser(), theme, name, string(raw)), nil
+}
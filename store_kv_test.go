@@ -0,0 +1,92 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreKV_Find(t *testing.T) {
+	values := map[string][]byte{
+		"default/page":  []byte("<!-- layouts/base -->content"),
+		"default/empty": {},
+	}
+	outage := errors.New("connection refused")
+
+	get := func(_ context.Context, key string) ([]byte, error) {
+		switch key {
+		case "default/down":
+			return nil, outage
+		case "default/gone":
+			return nil, fmt.Errorf("no such key: %w", ErrTemplateNotFound)
+		}
+		return values[key], nil
+	}
+	store := NewStoreKV(get, nil)
+
+	tests := []struct {
+		name        string
+		template    string
+		wantPath    string
+		wantContent string
+		wantErr     error
+	}{
+		{"found", "page", "layouts/base", "content", nil},
+		{"empty value", "empty", "empty", "", nil},
+		{"nil value", "missing", "", "", ErrTemplateNotFound},
+		{"not found error", "gone", "", "", ErrTemplateNotFound},
+		{"failure", "down", "", "", outage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := store.Find(context.Background(), "default", tt.template)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, item)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "default", item.Theme())
+			assert.Equal(t, tt.template, item.Name())
+			assert.Equal(t, tt.wantPath, item.Path())
+			assert.Equal(t, tt.wantContent, item.Content())
+		})
+	}
+
+	_, err := store.Find(context.Background(), "default", "down")
+	assert.EqualError(t, err, "store kv: failed to get template default/down at default/down: connection refused")
+}
+
+func TestStoreKV_Key(t *testing.T) {
+	var keys []string
+	store := NewStoreKV(func(_ context.Context, key string) ([]byte, error) {
+		keys = append(keys, key)
+		return []byte("content"), nil
+	}, func(theme, name string) string {
+		return "templates:" + theme + ":" + name
+	})
+
+	_, err := store.Find(context.Background(), "default", "page")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"templates:default:page"}, keys)
+}
+
+func TestStoreKV_Theme(t *testing.T) {
+	store := NewStoreKV(func(_ context.Context, key string) ([]byte, error) {
+		return map[string][]byte{
+			"default/layout": []byte(`<main>{{block "content" .}}{{end}}</main>`),
+			"default/page":   []byte(`<!-- layout -->{{define "content"}}{{.}}{{end}}`),
+		}[key], nil
+	}, nil)
+
+	var buf strings.Builder
+	require.NoError(t, NewTheme("default", store).Write(context.Background(), &buf, "page", "hello"))
+	assert.Equal(t, "<main>hello</main>", buf.String())
+}
@@ -3,28 +3,82 @@ package got
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var _ Store = (*StoreMemory)(nil)
+var (
+	_ Store       = (*StoreMemory)(nil)
+	_ Lister      = (*StoreMemory)(nil)
+	_ StoreWriter = (*StoreMemory)(nil)
+)
 
 // StoreMemory is a store implementation that stores templates in memory.
 type StoreMemory struct {
 	templates sync.Map
+	parser    atomic.Pointer[DirectiveParser]
+}
+
+// memoryKey is the key of a template of a StoreMemory.
+type memoryKey struct {
+	theme, name string
 }
 
 func NewStoreMemory() *StoreMemory {
 	return &StoreMemory{}
 }
 
+// DirectiveParser returns the parser extracting the layout directive of
+// templates, CommentDirective by default.
+func (s *StoreMemory) DirectiveParser() DirectiveParser {
+	if parser := s.parser.Load(); parser != nil {
+		return *parser
+	}
+	return CommentDirective
+}
+
+// SetDirectiveParser sets the parser extracting the layout directive of
+// templates added afterwards.
+func (s *StoreMemory) SetDirectiveParser(parser DirectiveParser) {
+	s.parser.Store(&parser)
+}
+
+// Add adds a template, its modification time being the time it is added.
 func (s *StoreMemory) Add(theme, name, content string) {
-	s.templates.Store(theme+name, newTemplate(theme, name, content))
+	item := newTemplateWith(s.DirectiveParser(), theme, name, content)
+	item.info.ModTime = time.Now()
+	s.templates.Store(memoryKey{theme, name}, item)
+}
+
+// Save adds a template as is, without parsing its directive.
+func (s *StoreMemory) Save(_ context.Context, item Template) error {
+	s.templates.Store(memoryKey{item.Theme(), item.Name()}, item)
+	return nil
+}
+
+func (s *StoreMemory) Delete(_ context.Context, theme, name string) error {
+	s.templates.Delete(memoryKey{theme, name})
+	return nil
 }
 
 func (s *StoreMemory) Find(_ context.Context, theme, name string) (Template, error) {
-	if v, ok := s.templates.Load(theme + name); ok {
+	if v, ok := s.templates.Load(memoryKey{theme, name}); ok {
 		return v.(Template), nil
 	}
 
 	return nil, fmt.Errorf("store memory: template %s/%s not found: %w", theme, name, ErrTemplateNotFound)
 }
+
+func (s *StoreMemory) List(_ context.Context, theme string) ([]string, error) {
+	var names []string
+	s.templates.Range(func(_, value any) bool {
+		if tpl := value.(Template); tpl.Theme() == theme {
+			names = append(names, tpl.Name())
+		}
+		return true
+	})
+	slices.Sort(names)
+	return names, nil
+}
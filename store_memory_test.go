@@ -249,7 +249,7 @@ func TestStoreMemory_ConcurrentAccess(t *testing.T) {
 func TestStoreMemory_KeyGeneration(t *testing.T) {
 	store := NewStoreMemory()
 
-	// Test that theme+name is used as the key
+	// Test that theme and name are both part of the key
 	store.Add("theme1", "name1", "<div>Content 1</div>")
 	store.Add("theme1", "name2", "<div>Content 2</div>")
 	store.Add("theme2", "name1", "<div>Content 3</div>")
@@ -269,6 +269,18 @@ func TestStoreMemory_KeyGeneration(t *testing.T) {
 
 	assert.NotEqual(t, tmpl1.Content(), tmpl2.Content(), "Different templates should have different content")
 	assert.NotEqual(t, tmpl1.Content(), tmpl3.Content(), "Templates with same name but different themes should have different content")
+
+	// theme and name don't collide when concatenated
+	store.Add("d", "efaultlayout.html", "<div>d</div>")
+	store.Add("default", "layout.html", "<div>default</div>")
+
+	tpl, err := store.Find(context.Background(), "d", "efaultlayout.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<div>d</div>", tpl.Content())
+
+	tpl, err = store.Find(context.Background(), "default", "layout.html")
+	require.NoError(t, err)
+	assert.Equal(t, "<div>default</div>", tpl.Content())
 }
 
 func TestStoreMemory_OverwriteTemplate(t *testing.T) {
@@ -369,3 +381,35 @@ func TestStoreMemory_Performance(t *testing.T) {
 	assert.Less(t, addDuration, time.Second, "Add operation took too long: %v", addDuration)
 	assert.Less(t, findDuration, time.Second, "Find operation took too long: %v", findDuration)
 }
+
+func TestStoreMemory_List(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("theme1", "b", "b")
+	store.Add("theme1", "a", "a")
+	store.Add("theme2", "c", "c")
+
+	names, err := store.List(context.Background(), "theme1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, names)
+
+	names, err = store.List(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestStoreMemory_SaveDelete(t *testing.T) {
+	store := NewStoreMemory()
+	item := &tmpl{theme: "theme", name: "a", path: "layouts/base", content: "a"}
+
+	require.NoError(t, store.Save(context.Background(), item))
+
+	found, err := store.Find(context.Background(), "theme", "a")
+	require.NoError(t, err)
+	assert.Same(t, item, found)
+
+	require.NoError(t, store.Delete(context.Background(), "theme", "a"))
+	require.NoError(t, store.Delete(context.Background(), "theme", "a"))
+
+	_, err = store.Find(context.Background(), "theme", "a")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
@@ -0,0 +1,152 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+var (
+	_ Store       = (*StoreRetry)(nil)
+	_ Lister      = (*StoreRetry)(nil)
+	_ Pinger      = (*StoreRetry)(nil)
+	_ BatchFinder = (*StoreRetry)(nil)
+)
+
+// RetryOptions configure a StoreRetry.
+type RetryOptions struct {
+	// Attempts is the maximum number of calls to the store per request,
+	// 3 if zero.
+	Attempts int
+
+	// MinBackoff is the delay before the first retry, 50 milliseconds if
+	// zero. It doubles on every retry, up to MaxBackoff, 2 seconds if zero.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Retryable reports whether a failed request is worth retrying. By
+	// default all errors are, but ErrTemplateNotFound and context errors.
+	Retryable func(err error) bool
+}
+
+// StoreRetry is a store wrapper retrying failed requests, such as those
+// failed by a network blip, with exponential backoff and jitter.
+//
+// Retries stop when the context is done or its deadline would pass before
+// the next attempt. Wrap it in a StoreBreaker for outages that retries
+// won't get through.
+type StoreRetry struct {
+	store   Store
+	options RetryOptions
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewStoreRetry(store Store, options RetryOptions) *StoreRetry {
+	if options.Attempts <= 0 {
+		options.Attempts = 3
+	}
+	if options.MinBackoff <= 0 {
+		options.MinBackoff = 50 * time.Millisecond
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = 2 * time.Second
+	}
+	options.MaxBackoff = max(options.MaxBackoff, options.MinBackoff)
+	if options.Retryable == nil {
+		options.Retryable = retryable
+	}
+	return &StoreRetry{store: store, options: options, sleep: sleep}
+}
+
+func (s *StoreRetry) Find(ctx context.Context, theme, name string) (item Template, err error) {
+	err = s.do(ctx, func() error {
+		item, err = s.store.Find(ctx, theme, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// FindMany finds the templates with FindMany when the store implements
+// BatchFinder, one by one otherwise, retrying failures.
+func (s *StoreRetry) FindMany(ctx context.Context, theme string, names []string) (items map[string]Template, err error) {
+	err = s.do(ctx, func() error {
+		items, err = findMany(ctx, s.store, theme, names)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// List lists the templates of the store, retrying failures. Stores not
+// implementing Lister fail with ErrNotLister.
+func (s *StoreRetry) List(ctx context.Context, theme string) (names []string, err error) {
+	lister, ok := s.store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("store retry: failed to list templates of %s: %w", theme, ErrNotLister)
+	}
+
+	err = s.do(ctx, func() error {
+		names, err = lister.List(ctx, theme)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Ping pings the store once if it implements Pinger, so health checks
+// report failures promptly.
+func (s *StoreRetry) Ping(ctx context.Context) error {
+	if pinger, ok := s.store.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// do calls fn until it succeeds, fails with an error not worth retrying,
+// or the attempts or the time of the context run out.
+func (s *StoreRetry) do(ctx context.Context, fn func() error) error {
+	backoff := s.options.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == s.options.Attempts || !s.options.Retryable(err) {
+			return err
+		}
+
+		// equal jitter, so concurrent retries spread out
+		delay := backoff/2 + rand.N(backoff/2+1)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		if err1 := s.sleep(ctx, delay); err1 != nil {
+			return fmt.Errorf("store retry: %w: %w", err1, err)
+		}
+
+		backoff = min(backoff*2, s.options.MaxBackoff)
+	}
+}
+
+func retryable(err error) bool {
+	return !errors.Is(err, ErrTemplateNotFound) && !isContextErr(err)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
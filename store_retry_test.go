@@ -0,0 +1,205 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRetry(store Store, options RetryOptions) (*StoreRetry, *[]time.Duration) {
+	var delays []time.Duration
+	retry := NewStoreRetry(store, options)
+	retry.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return retry, &delays
+}
+
+// blipStore is a memory store failing its first failures calls.
+type blipStore struct {
+	*StoreMemory
+	failures int
+	calls    int
+}
+
+func (s *blipStore) Find(ctx context.Context, theme, name string) (Template, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, errors.New("connection reset")
+	}
+	return s.StoreMemory.Find(ctx, theme, name)
+}
+
+func (s *blipStore) List(ctx context.Context, theme string) ([]string, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, errors.New("connection reset")
+	}
+	return s.StoreMemory.List(ctx, theme)
+}
+
+func TestNewStoreRetry(t *testing.T) {
+	retry := NewStoreRetry(NewStoreMemory(), RetryOptions{})
+	assert.Equal(t, 3, retry.options.Attempts)
+	assert.Equal(t, 50*time.Millisecond, retry.options.MinBackoff)
+	assert.Equal(t, 2*time.Second, retry.options.MaxBackoff)
+
+	retry = NewStoreRetry(NewStoreMemory(), RetryOptions{MinBackoff: 5 * time.Second})
+	assert.Equal(t, 5*time.Second, retry.options.MaxBackoff)
+}
+
+func TestStoreRetry_Find(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		wantErr   bool
+		wantCalls int
+	}{
+		{"first attempt", 0, false, 1},
+		{"after a blip", 1, false, 2},
+		{"last attempt", 3, false, 4},
+		{"attempts exhausted", 4, true, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &blipStore{StoreMemory: NewStoreMemory(), failures: tt.failures}
+			store.Add("test", "page", "content")
+
+			retry, delays := newTestRetry(store, RetryOptions{
+				Attempts:   4,
+				MinBackoff: 100 * time.Millisecond,
+				MaxBackoff: 300 * time.Millisecond,
+			})
+
+			item, err := retry.Find(context.Background(), "test", "page")
+			if tt.wantErr {
+				assert.EqualError(t, err, "connection reset")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "content", item.Content())
+			}
+			assert.Equal(t, tt.wantCalls, store.calls)
+			assert.Len(t, *delays, tt.wantCalls-1)
+
+			// jittered exponential backoff
+			bounds := []time.Duration{100, 200, 300}
+			for i, d := range *delays {
+				assert.GreaterOrEqual(t, d, bounds[i]*time.Millisecond/2)
+				assert.LessOrEqual(t, d, bounds[i]*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestStoreRetry_NotRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"not found", ErrTemplateNotFound},
+		{"canceled", context.Canceled},
+		{"deadline", context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &flakyStore{StoreMemory: NewStoreMemory(), err: tt.err}
+			retry, delays := newTestRetry(store, RetryOptions{})
+
+			_, err := retry.Find(context.Background(), "test", "page")
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, 1, store.finds)
+			assert.Empty(t, *delays)
+		})
+	}
+
+	store := &flakyStore{StoreMemory: NewStoreMemory(), err: errors.New("bad request")}
+	retry, _ := newTestRetry(store, RetryOptions{Retryable: func(error) bool { return false }})
+	_, err := retry.Find(context.Background(), "test", "page")
+	assert.Error(t, err)
+	assert.Equal(t, 1, store.finds)
+}
+
+func TestStoreRetry_Context(t *testing.T) {
+	outage := errors.New("timeout")
+
+	t.Run("deadline before the next attempt", func(t *testing.T) {
+		store := &flakyStore{StoreMemory: NewStoreMemory(), err: outage}
+		retry, delays := newTestRetry(store, RetryOptions{MinBackoff: time.Hour, MaxBackoff: time.Hour})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		_, err := retry.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, outage)
+		assert.Equal(t, 1, store.finds)
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("canceled while waiting", func(t *testing.T) {
+		store := &flakyStore{StoreMemory: NewStoreMemory(), err: outage}
+		retry := NewStoreRetry(store, RetryOptions{MinBackoff: time.Hour})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		_, err := retry.Find(ctx, "test", "page")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, err, outage)
+		assert.Equal(t, 1, store.finds)
+	})
+}
+
+func TestStoreRetry_List(t *testing.T) {
+	store := &blipStore{StoreMemory: NewStoreMemory(), failures: 1}
+	store.Add("test", "page", "content")
+
+	retry, _ := newTestRetry(store, RetryOptions{})
+	names, err := retry.List(context.Background(), "test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"page"}, names)
+	assert.Equal(t, 2, store.calls)
+
+	_, err = NewStoreRetry(&MockStore{}, RetryOptions{}).List(context.Background(), "test")
+	assert.ErrorIs(t, err, ErrNotLister)
+}
+
+func TestStoreRetry_Ping(t *testing.T) {
+	store := &pingStore{StoreMemory: NewStoreMemory(), err: errors.New("down")}
+	retry, delays := newTestRetry(store, RetryOptions{})
+
+	assert.ErrorIs(t, retry.Ping(context.Background()), store.err)
+	assert.Equal(t, 1, store.pings)
+	assert.Empty(t, *delays)
+
+	assert.NoError(t, NewStoreRetry(NewStoreMemory(), RetryOptions{}).Ping(context.Background()))
+}
+
+func TestStoreRetry_FindMany(t *testing.T) {
+	store := &blipStore{StoreMemory: NewStoreMemory(), failures: 1}
+	store.Add("test", "a", "content a")
+	store.Add("test", "b", "content b")
+
+	retry, delays := newTestRetry(store, RetryOptions{})
+	items, err := retry.FindMany(context.Background(), "test", []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "content b", items["b"].Content())
+	// a failed attempt, then a find per name
+	assert.Equal(t, 4, store.calls)
+	assert.Len(t, *delays, 1)
+
+	batch := &batchStore{StoreMemory: NewStoreMemory()}
+	batch.Add("test", "a", "content a")
+	items, err = NewStoreRetry(batch, RetryOptions{}).FindMany(context.Background(), "test", []string{"a", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, [][]string{{"test", "a", "missing"}}, batch.batches)
+	assert.Empty(t, batch.finds)
+}
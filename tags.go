@@ -0,0 +1,89 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"slices"
+)
+
+// ErrTagsNotSupported is returned by InvalidateTag when the page cache
+// backend doesn't implement TagInvalidator.
+var ErrTagsNotSupported = errors.New("page cache does not support tags")
+
+var errTagsNotBound = errors.New("cache_tag: function is not bound to a render")
+
+// cacheTagFn is the type of the cache_tag function, which tags the render
+// with what it depends on, so that the cached page can be invalidated when
+// that content changes:
+//
+//	{{cache_tag (printf "product:%d" .Product.ID) "nav"}}
+type cacheTagFn func(tags ...string) (string, error)
+
+// defaultTagsFuncs returns the unbound cache_tag function when funcMap has
+// none.
+func defaultTagsFuncs(funcMap template.FuncMap) template.FuncMap {
+	if _, ok := funcMap["cache_tag"]; ok {
+		return nil
+	}
+	return template.FuncMap{
+		"cache_tag": cacheTagFn(func(...string) (string, error) { return "", errTagsNotBound }),
+	}
+}
+
+// boundTagsFuncs returns the name of the cache_tag function of funcMap,
+// unless it was replaced by another function.
+func boundTagsFuncs(funcMap template.FuncMap) []string {
+	if _, ok := funcMap["cache_tag"].(cacheTagFn); ok {
+		return []string{"cache_tag"}
+	}
+	return nil
+}
+
+// tags collects the deduplicated tags of a render.
+type tags []string
+
+func (t *tags) reset() {
+	*t = (*t)[:0]
+}
+
+func (t *tags) funcs(funcMap template.FuncMap) template.FuncMap {
+	if len(boundTagsFuncs(funcMap)) == 0 {
+		return nil
+	}
+	return template.FuncMap{
+		"cache_tag": cacheTagFn(func(items ...string) (string, error) {
+			for _, tag := range items {
+				if tag != "" && !slices.Contains(*t, tag) {
+					*t = append(*t, tag)
+				}
+			}
+			return "", nil
+		}),
+	}
+}
+
+// TagInvalidator is implemented by page cache backends able to delete the
+// pages rendered with a tag, see CachedPage.Result.
+type TagInvalidator interface {
+	InvalidateTag(ctx context.Context, tag string) error
+}
+
+// InvalidateTag deletes the cached pages tagged with tag by cache_tag, so
+// that they are rendered again on their next request.
+func (t *Theme) InvalidateTag(ctx context.Context, tag string) error {
+	backend := t.PageCache().Backend
+	if backend == nil {
+		return nil
+	}
+
+	invalidator, ok := backend.(TagInvalidator)
+	if !ok {
+		return fmt.Errorf("theme: failed to invalidate tag %s of %s: %w", tag, t.name, ErrTagsNotSupported)
+	}
+	if err := invalidator.InvalidateTag(ctx, tag); err != nil {
+		return fmt.Errorf("theme: failed to invalidate tag %s of %s: %w", tag, t.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package got
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTags_Funcs(t *testing.T) {
+	funcs := defaultTagsFuncs(template.FuncMap{})
+	_, err := funcs["cache_tag"].(cacheTagFn)("nav")
+	assert.ErrorIs(t, err, errTagsNotBound)
+
+	assert.Nil(t, defaultTagsFuncs(template.FuncMap{"cache_tag": func() string { return "" }}))
+	assert.Nil(t, boundTagsFuncs(template.FuncMap{"cache_tag": func() string { return "" }}))
+
+	var tags tags
+	fn := tags.funcs(funcs)["cache_tag"].(cacheTagFn)
+	_, err = fn("nav", "product:1", "")
+	require.NoError(t, err)
+	_, err = fn("nav")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nav", "product:1"}, []string(tags))
+
+	tags.reset()
+	assert.Empty(t, tags)
+}
+
+func TestTheme_Render_Tags(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", `{{cache_tag "nav"}}<nav></nav>{{template "content" .}}`)
+	store.Add("test", "product", `<!-- layouts/base -->{{define "content"}}{{cache_tag (printf "product:%d" .ID)}}<p>{{.Name}}</p>{{end}}`)
+	store.Add("test", "plain", `<p>plain</p>`)
+
+	theme := NewTheme("test", store)
+
+	var buf bytes.Buffer
+	result, err := theme.Render(context.Background(), &buf, "product", map[string]any{"ID": 42, "Name": "Pen"})
+	require.NoError(t, err)
+	assert.Equal(t, "<nav></nav><p>Pen</p>", buf.String())
+	assert.Equal(t, []string{"nav", "product:42"}, result.Tags)
+
+	result, err = theme.Render(context.Background(), &buf, "product", map[string]any{"ID": 7})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nav", "product:7"}, result.Tags, "tags are collected per render")
+
+	result, err = theme.Render(context.Background(), &buf, "plain", nil)
+	require.NoError(t, err)
+	assert.Nil(t, result.Tags)
+}
+
+func TestTheme_InvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("test", "product", `{{cache_tag "nav" (printf "product:%d" .ID)}}<p>{{.ID}}</p>`)
+	store.Add("test", "about", `{{cache_tag "nav"}}<p>about</p>`)
+
+	theme := NewTheme("test", store)
+	require.NoError(t, theme.InvalidateTag(ctx, "nav"), "no page cache")
+
+	backend := NewPageCacheMemory(0)
+	theme.SetPageCache(PageCacheOptions{Backend: backend})
+
+	render := func(name, key string, data any) {
+		_, result, err := theme.RenderPage(ctx, name, key, data)
+		require.NoError(t, err)
+		assert.Contains(t, result.Tags, "nav")
+	}
+	render("product", "/p/1", map[string]any{"ID": 1})
+	render("product", "/p/2", map[string]any{"ID": 2})
+	render("about", "/about", nil)
+	assert.Equal(t, 3, backend.Len())
+
+	require.NoError(t, theme.InvalidateTag(ctx, "product:1"))
+	assert.Equal(t, 2, backend.Len())
+	_, ok, _ := backend.Get(ctx, theme.pageKey(theme.gen.Load(), "product", "/p/1"))
+	assert.False(t, ok)
+
+	require.NoError(t, theme.InvalidateTag(ctx, "nav"))
+	assert.Equal(t, 0, backend.Len())
+
+	require.NoError(t, theme.InvalidateTag(ctx, "missing"))
+
+	theme.SetPageCache(PageCacheOptions{Backend: failingPageCache{}})
+	assert.ErrorIs(t, theme.InvalidateTag(ctx, "nav"), ErrTagsNotSupported)
+}
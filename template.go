@@ -1,8 +1,13 @@
 package got
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"path"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var commentRe = regexp.MustCompile(`^\s*<!--(.*?)-->`)
@@ -14,25 +19,184 @@ type Template interface {
 	Content() string
 }
 
+// Metadata is implemented by templates carrying metadata extracted by a
+// DirectiveParser, such as front matter fields.
+type Metadata interface {
+	Meta() map[string]string
+}
+
+// TemplateInfo describes the stored content of a template, for caches and
+// HTTP validators such as ETag and Last-Modified.
+type TemplateInfo struct {
+	// Hash identifies the content, such as its hex SHA-256 digest or a
+	// version from the backend. It changes whenever the content does.
+	Hash string
+	// ModTime is when the template was last modified, zero if unknown.
+	ModTime time.Time
+	// Size is the size of the content in bytes.
+	Size int64
+	// ContentType is the media type of the template, such as
+	// "text/html; charset=utf-8".
+	ContentType string
+}
+
+// Statter is implemented by templates whose store describes their content,
+// see Stat. Stores may fill only the fields they know.
+type Statter interface {
+	Stat() TemplateInfo
+}
+
+// Stat returns the info of the template, the one provided by its store
+// completed with fields derived from its name and content: the SHA-256
+// digest of the content, its size, and a media type from the extension of
+// the name, HTML by default.
+func Stat(item Template) TemplateInfo {
+	var info TemplateInfo
+	if statter, ok := item.(Statter); ok {
+		info = statter.Stat()
+	}
+
+	if info.Hash == "" {
+		sum := sha256.Sum256([]byte(item.Content()))
+		info.Hash = hex.EncodeToString(sum[:])
+	}
+	if info.Size == 0 {
+		info.Size = int64(len(item.Content()))
+	}
+	if info.ContentType == "" {
+		info.ContentType = mime.TypeByExtension(path.Ext(item.Name()))
+		if info.ContentType == "" {
+			info.ContentType = "text/html; charset=utf-8"
+		}
+	}
+	return info
+}
+
+// Directive is the result of parsing the layout directive of a template.
+type Directive struct {
+	// Path is the name of the template the content extends, the template
+	// name itself when it extends nothing.
+	Path string
+	// Meta holds additional fields declared by the directive, if any.
+	Meta map[string]string
+	// Content is the template content without the directive.
+	Content string
+}
+
+// DirectiveParser extracts the layout directive from the content of a
+// template, so projects can use their own directive syntax.
+type DirectiveParser interface {
+	ParseDirective(name, content string) Directive
+}
+
+// DirectiveParserFunc is an adapter to use ordinary functions as
+// DirectiveParser.
+type DirectiveParserFunc func(name, content string) Directive
+
+func (f DirectiveParserFunc) ParseDirective(name, content string) Directive {
+	return f(name, content)
+}
+
+var (
+	// CommentDirective reads the path from a leading HTML comment:
+	//
+	//	<!-- layouts/base -->
+	CommentDirective DirectiveParser = commentDirective{}
+
+	// FrontMatterDirective reads "key: value" fields from a leading front
+	// matter block, the path from the "layout" field:
+	//
+	//	---
+	//	layout: layouts/base
+	//	title: Home
+	//	---
+	FrontMatterDirective DirectiveParser = frontMatterDirective{}
+)
+
+type (
+	commentDirective     struct{}
+	frontMatterDirective struct{}
+)
+
+func (commentDirective) ParseDirective(name, content string) Directive {
+	p := name
+	if comment := commentRe.FindStringSubmatch(content); len(comment) > 0 {
+		content = commentRe.ReplaceAllString(content, "")
+		p = strings.TrimSpace(comment[1])
+	}
+
+	return Directive{Path: p, Content: content}
+}
+
+func (frontMatterDirective) ParseDirective(name, content string) Directive {
+	d := Directive{Path: name, Content: content}
+
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		if rest, ok = strings.CutPrefix(content, "---\r\n"); !ok {
+			return d
+		}
+	}
+
+	// header is the length of the front matter up to the closing "---",
+	// lines may end with CRLF
+	header := len(content) - len(rest)
+	var fields []string
+	for {
+		line, next, found := strings.Cut(rest, "\n")
+		if strings.TrimSuffix(line, "\r") == "---" {
+			header += len(line)
+			break
+		}
+		if !found {
+			return d
+		}
+		fields = append(fields, line)
+		header += len(line) + 1
+		rest = next
+	}
+
+	d.Meta = make(map[string]string)
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+		d.Meta[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	if layout := d.Meta["layout"]; layout != "" {
+		d.Path = layout
+	}
+
+	// keep the lines of the front matter, so line numbers in errors match
+	// the source
+	d.Content = strings.Repeat("\n", strings.Count(content[:header], "\n")) + content[header:]
+	return d
+}
+
 type tmpl struct {
 	theme   string
 	path    string
 	name    string
 	content string
+	meta    map[string]string
+	info    TemplateInfo
 }
 
 func newTemplate(theme, name, content string) *tmpl {
-	p := name
-	if comment := commentRe.FindStringSubmatch(content); len(comment) > 0 {
-		content = commentRe.ReplaceAllString(content, "")
-		p = strings.TrimSpace(comment[1])
-	}
+	return newTemplateWith(CommentDirective, theme, name, content)
+}
+
+func newTemplateWith(parser DirectiveParser, theme, name, content string) *tmpl {
+	d := parser.ParseDirective(name, content)
 
 	return &tmpl{
 		theme:   theme,
 		name:    name,
-		path:    p,
-		content: content,
+		path:    d.Path,
+		content: d.Content,
+		meta:    d.Meta,
 	}
 }
 
@@ -51,3 +215,11 @@ func (t *tmpl) Name() string {
 func (t *tmpl) Content() string {
 	return t.content
 }
+
+func (t *tmpl) Meta() map[string]string {
+	return t.meta
+}
+
+func (t *tmpl) Stat() TemplateInfo {
+	return t.info
+}
@@ -1,7 +1,11 @@
 package got
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -299,3 +303,209 @@ func TestTemplateEdgeCases(t *testing.T) {
 		assert.Equal(t, "layouts/base", tmpl.Path()) // Comment path takes precedence
 	})
 }
+
+func TestFrontMatterDirective(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected Directive
+	}{
+		{
+			name:    "layout and fields",
+			content: "---\nlayout: layouts/base\ntitle: Home: Welcome\n---\n<p>{{.}}</p>",
+			expected: Directive{
+				Path:    "layouts/base",
+				Meta:    map[string]string{"layout": "layouts/base", "title": "Home: Welcome"},
+				Content: "\n\n\n\n<p>{{.}}</p>",
+			},
+		},
+		{
+			name:    "fields without layout",
+			content: "---\ntitle: Home\n---\n<p></p>",
+			expected: Directive{
+				Path:    "page",
+				Meta:    map[string]string{"title": "Home"},
+				Content: "\n\n\n<p></p>",
+			},
+		},
+		{
+			name:    "CRLF line endings",
+			content: "---\r\nlayout: layouts/base\r\ntitle: Home\r\n---\r\n<p></p>",
+			expected: Directive{
+				Path:    "layouts/base",
+				Meta:    map[string]string{"layout": "layouts/base", "title": "Home"},
+				Content: "\n\n\n\n<p></p>",
+			},
+		},
+		{
+			name:    "empty front matter",
+			content: "---\n---\n<p></p>",
+			expected: Directive{
+				Path:    "page",
+				Meta:    map[string]string{},
+				Content: "\n\n<p></p>",
+			},
+		},
+		{
+			name:    "empty front matter with CRLF",
+			content: "---\r\n---\r\n<p></p>",
+			expected: Directive{
+				Path:    "page",
+				Meta:    map[string]string{},
+				Content: "\n\n<p></p>",
+			},
+		},
+		{
+			name:     "delimiter prefix is not a closing line",
+			content:  "---\nlayout: base\n----\n<p></p>",
+			expected: Directive{Path: "page", Content: "---\nlayout: base\n----\n<p></p>"},
+		},
+		{
+			name:     "no front matter",
+			content:  "<p>---</p>",
+			expected: Directive{Path: "page", Content: "<p>---</p>"},
+		},
+		{
+			name:     "unterminated front matter",
+			content:  "---\nlayout: base\n<p></p>",
+			expected: Directive{Path: "page", Content: "---\nlayout: base\n<p></p>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FrontMatterDirective.ParseDirective("page", tt.content))
+		})
+	}
+}
+
+func TestCommentDirective(t *testing.T) {
+	d := CommentDirective.ParseDirective("page", "<!-- layouts/base -->\n<p></p>")
+	assert.Equal(t, Directive{Path: "layouts/base", Content: "\n<p></p>"}, d)
+}
+
+func TestNewTemplateWith(t *testing.T) {
+	parser := DirectiveParserFunc(func(name, content string) Directive {
+		path, body, _ := strings.Cut(content, "|")
+		return Directive{Path: path, Meta: map[string]string{"source": name}, Content: body}
+	})
+
+	result := newTemplateWith(parser, "default", "page", "layouts/base|<p></p>")
+	assert.Equal(t, "layouts/base", result.Path())
+	assert.Equal(t, "<p></p>", result.Content())
+
+	var tpl Template = result
+	meta, ok := tpl.(Metadata)
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"source": "page"}, meta.Meta())
+}
+
+func TestStores_SetDirectiveParser(t *testing.T) {
+	ctx := context.Background()
+
+	memory := NewStoreMemory()
+	assert.Equal(t, CommentDirective, memory.DirectiveParser())
+	memory.SetDirectiveParser(FrontMatterDirective)
+	memory.Add("default", "page", "---\nlayout: base\n---\n<p></p>")
+
+	tpl, err := memory.Find(ctx, "default", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "base", tpl.Path())
+
+	fsStore := NewStoreFS(fstest.MapFS{
+		"default/page": &fstest.MapFile{Data: []byte("---\nlayout: base\n---\n<p></p>")},
+	})
+	assert.Equal(t, CommentDirective, fsStore.DirectiveParser())
+	fsStore.SetDirectiveParser(FrontMatterDirective)
+
+	tpl, err = fsStore.Find(ctx, "default", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "base", tpl.Path())
+
+	kvStore := NewStoreKV(func(context.Context, string) ([]byte, error) {
+		return []byte("---\nlayout: base\n---\n<p></p>"), nil
+	}, nil)
+	assert.Equal(t, CommentDirective, kvStore.DirectiveParser())
+	kvStore.SetDirectiveParser(FrontMatterDirective)
+
+	tpl, err = kvStore.Find(ctx, "default", "page")
+	require.NoError(t, err)
+	assert.Equal(t, "base", tpl.Path())
+}
+
+func TestStat(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		item Template
+		want TemplateInfo
+	}{
+		{
+			name: "derived",
+			item: newTemplate("test", "page.html", "<!-- base -->hello"),
+			want: TemplateInfo{
+				Hash:        "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				Size:        5,
+				ContentType: "text/html; charset=utf-8",
+			},
+		},
+		{
+			name: "without extension",
+			item: newTemplate("test", "page", "hello"),
+			want: TemplateInfo{
+				Hash:        "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				Size:        5,
+				ContentType: "text/html; charset=utf-8",
+			},
+		},
+		{
+			name: "other extension",
+			item: newTemplate("test", "feed.xml", "hello"),
+			want: TemplateInfo{
+				Hash:        "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+				Size:        5,
+				ContentType: "text/xml; charset=utf-8",
+			},
+		},
+		{
+			name: "from the store",
+			item: &tmpl{
+				name:    "page.html",
+				content: "hello",
+				info:    TemplateInfo{Hash: "v42", ModTime: modTime},
+			},
+			want: TemplateInfo{
+				Hash:        "v42",
+				ModTime:     modTime,
+				Size:        5,
+				ContentType: "text/html; charset=utf-8",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Stat(tt.item))
+		})
+	}
+}
+
+func TestStores_Stat(t *testing.T) {
+	ctx := context.Background()
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	fsStore := NewStoreFS(fstest.MapFS{
+		"test/page.html": &fstest.MapFile{Data: []byte("hello"), ModTime: modTime},
+	})
+	item, err := fsStore.Find(ctx, "test", "page.html")
+	require.NoError(t, err)
+	assert.Equal(t, modTime, Stat(item).ModTime)
+
+	before := time.Now()
+	memoryStore := NewStoreMemory()
+	memoryStore.Add("test", "page.html", "hello")
+	item, err = memoryStore.Find(ctx, "test", "page.html")
+	require.NoError(t, err)
+	assert.False(t, Stat(item).ModTime.Before(before))
+}
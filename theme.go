@@ -1,37 +1,167 @@
 package got
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"regexp"
+	"log/slog"
+	"maps"
+	"path"
+	"runtime/pprof"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
-)
+	"text/template/parse"
+	"time"
 
-var (
-	defineRe   = regexp.MustCompile(`define\s+"([^"]+)"`)
-	templateRe = regexp.MustCompile(`(template|block)\s+"([^"]+)"`)
+	"golang.org/x/text/language"
 )
 
+// rootTree is the name given to the top-level tree of a parsed template
+// content; it can't collide with a name declared by {{define}}.
+const rootTree = "\x00root"
+
+// compiledSizeFactor approximates the memory held by a compiled template
+// (parse trees, escaped copies) as a multiple of its source size.
+const compiledSizeFactor = 4
+
+// parsedSizeFactor approximates the memory held by the parse trees of a
+// source template as a multiple of its size.
+const parsedSizeFactor = 2
+
+// ownerSize approximates the memory held by a memoized template owner.
+const ownerSize = 64
+
+// parseBuiltins names the predefined functions of text/template, so that
+// sources can be parsed without building a template.
+var parseBuiltins = func() map[string]any {
+	builtins := make(map[string]any)
+	for _, name := range []string{
+		"and", "call", "html", "index", "slice", "js", "len", "not", "or",
+		"print", "printf", "println", "urlquery",
+		"eq", "ge", "gt", "le", "lt", "ne",
+	} {
+		builtins[name] = true
+	}
+	return builtins
+}()
+
+// compiled is a template set built for a page, along with its approximate
+// size in bytes.
+//
+// html/template refuses to clone a template once it has been executed, so
+// the sets that must stay unexecuted, for Compiled, pooled instances and
+// error reports, are assembled again from the parse trees when needed.
+type compiled struct {
+	tpl  *template.Template
+	size int64
+
+	// assemble returns a new, unexecuted template set of the page.
+	assemble func() (*template.Template, error)
+
+	protoOnce sync.Once
+	proto     *template.Template
+
+	// instances holds sets bound to their own render state, so that Limits
+	// are accounted and assets collected per render. It is nil when the set
+	// needs no render state.
+	instances *sync.Pool
+	limits    Limits
+
+	// buffered is set when the set outputs markers filled once the render
+	// completes, such as the collected assets or placeholders.
+	buffered bool
+
+	// tagged is set when the set tags its renders with cache_tag.
+	tagged bool
+
+	// funcs are the functions the set was built with, needed to bind
+	// assembled sets.
+	funcs template.FuncMap
+
+	// aliases returns the deprecated aliases of the given functions, so the
+	// aliases of bound functions are bound too.
+	aliases func(template.FuncMap) template.FuncMap
+
+	// info describes the templates of the set, see Theme.Stat.
+	info TemplateInfo
+}
+
+// prototype returns an unexecuted set of the page, assembled once.
+func (c *compiled) prototype() *template.Template {
+	c.protoOnce.Do(func() {
+		c.proto, _ = c.assemble()
+	})
+	return c.proto
+}
+
+func compiledSize(c *compiled) int64 {
+	return c.size
+}
+
+func parsedSize(p *parsed) int64 {
+	return p.size
+}
+
+func ownerEntrySize(*Theme) int64 {
+	return ownerSize
+}
+
 type Theme struct {
 	name    string
 	store   Store
-	cache   sync.Map
+	cache   *cache[*compiled]
+	trees   *cache[*parsed]
+	owners  *cache[*Theme]
 	funcMap sync.Map
 	debug   atomic.Bool
+	eager   atomic.Bool
+	csp     atomic.Bool
+	include atomic.Pointer[[]string]
+	limits  atomic.Pointer[Limits]
+	policy  atomic.Pointer[TrustPolicy]
+	logger  atomic.Pointer[slog.Logger]
 	parent  atomic.Pointer[Theme]
+
+	// parentRef references the parent by name when it is resolved lazily,
+	// resolved is the parent the cached templates were built against.
+	parentRef atomic.Pointer[parentRef]
+	resolved  atomic.Pointer[Theme]
+
+	deprecated sync.Map
+	warned     sync.Map
+	enrichers  atomic.Pointer[[]DataEnricher]
+	processors atomic.Pointer[[]PostProcessor]
+	pageCache  atomic.Pointer[PageCacheOptions]
+	pages      pageFlight
+	access     atomic.Pointer[AccessLog]
+	sinks      atomic.Pointer[[]EventSink]
+
+	// gen counts resets, funcs is the snapshot of buildFuncs.
+	gen   atomic.Uint64
+	funcs atomic.Pointer[funcsSnapshot]
 }
 
 func NewTheme(name string, store Store) *Theme {
+	budget := NewCacheBudget(0)
 	return &Theme{
-		name:  name,
-		store: store,
+		name:   name,
+		store:  store,
+		cache:  newCache(budget, compiledSize),
+		trees:  newCache(budget, parsedSize),
+		owners: newCache(budget, ownerEntrySize),
 	}
 }
 
+// Clear drops the cached templates, so they are built again from the
+// stores. Pages cached by RenderPage and WritePage before are no longer
+// served, see RenderPage.
 func (t *Theme) Clear() {
 	t.reset()
 }
@@ -53,15 +183,205 @@ func (t *Theme) SetDebug(debug bool) {
 	t.reset()
 }
 
+// Eager reports whether every template of the theme is registered into the
+// template set of each page.
+func (t *Theme) Eager() bool {
+	return t.eager.Load()
+}
+
+// SetEager makes every template of the theme and its parents available to
+// each page, not only the statically referenced ones, so they can be invoked
+// by a dynamic name with include:
+//
+//	{{include .Widget.Template .Widget}}
+//
+// Listing the templates requires the stores of the theme and its parents to
+// implement Lister, builds fail with ErrNotLister otherwise.
+func (t *Theme) SetEager(eager bool) {
+	t.eager.Store(eager)
+	t.reset()
+}
+
+// CSPHashes reports whether Render collects the hashes of inline scripts
+// and styles.
+func (t *Theme) CSPHashes() bool {
+	return t.csp.Load()
+}
+
+// SetCSPHashes makes Render collect the SHA-256 hashes of the inline
+// <script> and <style> blocks of each page, so handlers can send a matching
+// Content-Security-Policy header. Pages are then rendered into a buffer.
+func (t *Theme) SetCSPHashes(enabled bool) {
+	t.csp.Store(enabled)
+}
+
+// AutoInclude returns the templates parsed into every page, see
+// SetAutoInclude.
+func (t *Theme) AutoInclude() []string {
+	if patterns := t.include.Load(); patterns != nil {
+		return slices.Clone(*patterns)
+	}
+	return nil
+}
+
+// SetAutoInclude sets templates that are parsed into the template set of
+// every page regardless of static references, e.g. a library of shared
+// {{define}} components. Patterns use the path.Match syntax:
+//
+//	theme.SetAutoInclude("components/*", "macros.gohtml")
+//
+// Patterns with wildcards require the stores of the theme and its parents
+// to implement Lister, builds fail with ErrNotLister otherwise.
+func (t *Theme) SetAutoInclude(patterns ...string) {
+	patterns = slices.Clone(patterns)
+	t.include.Store(&patterns)
+	t.reset()
+}
+
+// CacheSize returns the approximate memory in bytes held by the caches of
+// the theme: compiled templates, parse trees and template owners.
+func (t *Theme) CacheSize() int64 {
+	return t.cache.Size() + t.trees.Size() + t.owners.Size()
+}
+
+// CacheBudget returns the memory budget in bytes of the caches of the theme,
+// zero means unlimited.
+func (t *Theme) CacheBudget() int64 {
+	return t.cache.Budget()
+}
+
+// SetCacheBudget limits the approximate memory in bytes held by the caches
+// of the theme, and of the themes sharing its budget, see UseCacheBudget.
+// The least recently used entries are evicted once the budget is exceeded.
+// Zero disables the limit.
+func (t *Theme) SetCacheBudget(budget int64) {
+	t.cache.SetBudget(budget)
+}
+
+// UseCacheBudget accounts the caches of the theme in the budget, which may
+// be shared with other themes. Each theme has a budget of its own
+// otherwise. The caches are cleared.
+//
+// A theme sharing a budget stays referenced by it until its entries are
+// evicted, so themes dropped at runtime should be cleared first.
+func (t *Theme) UseCacheBudget(budget *CacheBudget) {
+	t.cache.UseBudget(budget)
+	t.trees.UseBudget(budget)
+	t.owners.UseBudget(budget)
+	t.reset()
+}
+
+// Limits returns the resource limits enforced while rendering.
+func (t *Theme) Limits() Limits {
+	if limits := t.limits.Load(); limits != nil {
+		return *limits
+	}
+	return Limits{}
+}
+
+// SetLimits sets the resource limits enforced by the expensive functions of
+// Funcs while rendering, see Limits.
+func (t *Theme) SetLimits(limits Limits) {
+	t.limits.Store(&limits)
+	t.reset()
+}
+
+// TrustPolicy returns the policy controlling the trusted-content functions,
+// nil if they are not restricted.
+func (t *Theme) TrustPolicy() TrustPolicy {
+	if policy := t.policy.Load(); policy != nil {
+		return *policy
+	}
+	return nil
+}
+
+// SetTrustPolicy sets the policy controlling to_html, to_html_attr, to_js
+// and to_css, see TrustPolicy. A nil policy lifts any restriction.
+func (t *Theme) SetTrustPolicy(policy TrustPolicy) {
+	if policy == nil {
+		t.policy.Store(nil)
+	} else {
+		t.policy.Store(&policy)
+	}
+	t.reset()
+}
+
+// Logger returns the logger of the theme, slog.Default() if none is set.
+func (t *Theme) Logger() *slog.Logger {
+	if logger := t.logger.Load(); logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+func (t *Theme) SetLogger(logger *slog.Logger) {
+	t.logger.Store(logger)
+}
+
+// DeprecatedFuncs returns the deprecated function names of the theme, mapped
+// to the names of their replacements.
+func (t *Theme) DeprecatedFuncs() map[string]string {
+	deprecated := make(map[string]string)
+	t.deprecated.Range(func(key, value any) bool {
+		deprecated[key.(string)] = value.(string)
+		return true
+	})
+	return deprecated
+}
+
+// AddDeprecatedFuncs registers deprecated function names as aliases of their
+// replacements, so functions can be renamed without breaking existing
+// templates. The first use of an alias logs a warning.
+//
+//	theme.AddDeprecatedFuncs(map[string]string{"str_build": "str_concat"})
+func (t *Theme) AddDeprecatedFuncs(aliases map[string]string) {
+	for alias, name := range aliases {
+		t.deprecated.Store(alias, name)
+	}
+	t.reset()
+}
+
 func (t *Theme) Parent() *Theme {
+	if ref := t.parentRef.Load(); ref != nil {
+		parent, _ := ref.registry.Get(ref.name)
+		return parent
+	}
 	return t.parent.Load()
 }
 
 func (t *Theme) SetParent(parent *Theme) {
+	t.parentRef.Store(nil)
 	t.parent.Store(parent)
 	t.reset()
 }
 
+// SetParentName sets the parent to the theme registered under name in the
+// registry. The parent is resolved at render time, so registering another
+// theme under that name swaps the parent and invalidates the cache.
+func (t *Theme) SetParentName(registry *ThemeRegistry, name string) {
+	t.parentRef.Store(&parentRef{registry: registry, name: name})
+	t.parent.Store(nil)
+	t.reset()
+}
+
+type parentRef struct {
+	registry *ThemeRegistry
+	name     string
+}
+
+// syncParent resets the theme when its lazily resolved parent has changed
+// since the cached templates were built.
+func (t *Theme) syncParent() {
+	if t.parentRef.Load() == nil {
+		return
+	}
+
+	parent := t.Parent()
+	if old := t.resolved.Swap(parent); old != parent {
+		t.reset()
+	}
+}
+
 func (t *Theme) FuncMap() template.FuncMap {
 	funcMap := make(template.FuncMap)
 	t.funcMap.Range(func(key, value any) bool {
@@ -84,140 +404,843 @@ func (t *Theme) AddFuncMap(funcMap template.FuncMap) {
 }
 
 func (t *Theme) reset() {
+	gen := t.gen.Add(1)
 	t.cache.Clear()
+	t.trees.Clear()
+	t.owners.Clear()
+	t.purgePages(gen - 1)
 
-	if parent := t.parent.Load(); parent != nil {
+	if parent := t.Parent(); parent != nil {
 		parent.SetFuncMap(t.FuncMap())
 		parent.SetDebug(t.debug.Load())
 	}
 }
 
 func (t *Theme) Write(ctx context.Context, w io.Writer, name string, data any) error {
-	debug := t.debug.Load()
+	_, err := t.Render(ctx, w, name, data)
+	return err
+}
 
-	if !debug {
-		if tpl, ok := t.cache.Load(name); ok {
-			return tpl.(*template.Template).Execute(w, data)
+// Render writes the named page like Write and describes the result.
+func (t *Theme) Render(ctx context.Context, w io.Writer, name string, data any) (RenderResult, error) {
+	if !t.observed() {
+		return t.render(ctx, w, name, data)
+	}
+
+	start := time.Now()
+	t.emit(ctx, Event{Kind: EventRenderStart, Template: name, Time: start})
+
+	result, err := t.render(ctx, w, name, data)
+
+	t.emit(ctx, Event{Kind: EventRenderFinish, Template: name, Duration: time.Since(start), Err: err})
+	if err != nil {
+		t.emit(ctx, Event{Kind: EventError, Template: name, Err: err})
+	}
+	return result, err
+}
+
+func (t *Theme) render(ctx context.Context, w io.Writer, name string, data any) (RenderResult, error) {
+	var result RenderResult
+
+	c, err := t.compile(ctx, name)
+	if err != nil {
+		return result, err
+	}
+
+	if log := t.access.Load(); log != nil {
+		log.Record(name)
+	}
+
+	if data, err = t.enrich(ctx, data); err != nil {
+		return result, fmt.Errorf("theme: failed to enrich data of template %s/%s: %w", t.name, name, err)
+	}
+
+	processors := t.PostProcessors()
+
+	out := w
+	var buf *bytes.Buffer
+	if t.csp.Load() || len(processors) > 0 {
+		buf = bufferPool.Get().(*bytes.Buffer)
+		defer func() {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}()
+		out = buf
+	}
+
+	t.profile(ctx, name, func() {
+		err = c.execute(out, data, &result)
+	})
+	if err != nil {
+		return result, newExecError(t.name, name, c.prototype(), err)
+	}
+
+	if buf != nil {
+		page := buf.Bytes()
+		if len(processors) > 0 {
+			if page, err = t.process(ctx, name, page); err != nil {
+				return result, fmt.Errorf("theme: failed to process output of template %s/%s: %w", t.name, name, err)
+			}
+		}
+		if t.csp.Load() {
+			result.ScriptHashes, result.StyleHashes = cspHashes(page)
+		}
+		if _, err = w.Write(page); err != nil {
+			return result, err
 		}
 	}
 
-	tpl, err := t.buildTemplate(ctx, name)
+	return result, nil
+}
+
+// Compiled returns a clone of the compiled template set of the named page.
+//
+// The clone is owned by the caller: it can be extended with per-request
+// funcs or used to execute associated templates directly, without affecting
+// the theme cache and without re-parsing the page.
+func (t *Theme) Compiled(ctx context.Context, name string) (*template.Template, error) {
+	c, err := t.compile(ctx, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if !debug {
-		t.cache.Store(name, tpl)
+	tpl, err := c.assemble()
+	if err != nil {
+		return nil, fmt.Errorf("theme: failed to assemble template %s/%s: %w", t.name, name, err)
+	}
+
+	tpl.Funcs(new(assets).funcs(c.funcs, false))
+	tpl.Funcs(new(placeholders).funcs(c.funcs, false))
+	tpl.Funcs(new(tags).funcs(c.funcs))
+	return tpl, nil
+}
+
+// Stat describes the templates the named page is built from, for HTTP
+// validators of pages that depend on nothing else: the hash changes
+// whenever any of the templates does, the modification time is the latest
+// one, and the size is their total.
+//
+//	info, err := theme.Stat(ctx, "home.html")
+//	w.Header().Set("ETag", `"`+info.Hash+`"`)
+func (t *Theme) Stat(ctx context.Context, name string) (TemplateInfo, error) {
+	c, err := t.compile(ctx, name)
+	if err != nil {
+		return TemplateInfo{}, err
+	}
+	return c.info, nil
+}
+
+func (t *Theme) compile(ctx context.Context, name string) (*compiled, error) {
+	t.syncParent()
+
+	if t.debug.Load() {
+		return t.build(ctx, name)
+	}
+
+	key := cacheName(ctx, name)
+	if t.observed() {
+		if c, ok := t.cache.Load(key); ok {
+			t.emit(ctx, Event{Kind: EventCacheHit, Template: name, Cache: CacheTemplate})
+			return c, nil
+		}
+		t.emit(ctx, Event{Kind: EventCacheMiss, Template: name, Cache: CacheTemplate})
 	}
 
-	return tpl.Execute(w, data)
+	// the build is shared by the concurrent callers, it isn't canceled with
+	// the context of the first one
+	if ctx.Done() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	return t.cache.LoadOrBuild(key, func() (*compiled, error) {
+		return t.build(ctx, name)
+	})
 }
 
-func (t *Theme) buildTemplate(ctx context.Context, name string) (*template.Template, error) {
-	data := make(map[string]Template)
-	if err := t.findByName(ctx, data, name); err != nil {
+func (t *Theme) build(ctx context.Context, name string) (c *compiled, err error) {
+	t.profile(ctx, name, func() {
+		c, err = t.buildTemplate(ctx, name)
+	})
+	return
+}
+
+// profile runs fn with the theme and template name attached as pprof labels,
+// so CPU profiles attribute rendering time to specific templates.
+//
+// Only the goroutine is labeled, the caller's context is passed on to
+// stores unchanged.
+func (t *Theme) profile(ctx context.Context, name string, fn func()) {
+	pprof.Do(ctx, pprof.Labels("theme", t.name, "template", name), func(context.Context) {
+		fn()
+	})
+}
+
+func (t *Theme) buildTemplate(ctx context.Context, name string) (*compiled, error) {
+	funcs := t.buildFuncs()
+	if locale, ok := LocaleFrom(ctx); ok {
+		funcs = t.buildLocaleFuncs(locale)
+	}
+
+	deps := make(map[string]*dependency)
+	fetched := make(map[string]Template)
+	t.prefetch(ctx, deps, fetched, slices.Concat([]string{name}, slices.DeleteFunc(t.AutoInclude(), hasMeta)))
+
+	if err := t.findByName(ctx, deps, fetched, funcs, name); err != nil {
+		return nil, err
+	}
+
+	if err := t.findIncluded(ctx, deps, fetched, funcs); err != nil {
 		return nil, err
 	}
 
-	page, ok := data[name]
+	page, ok := deps[name]
 	if !ok {
 		return nil, fmt.Errorf("theme: template %s/%s not found: %w", t.name, name, ErrTemplateNotFound)
 	}
 
 	for page.Path() != page.Name() {
-		page = data[page.Path()]
+		page = deps[page.Path()]
 	}
 
-	funcs := t.FuncMap()
+	var size int64
+	for _, dep := range deps {
+		size += int64(len(dep.Name()) + len(dep.Content()))
+	}
+
+	others := make([]*dependency, 0, len(deps)-1)
+	for _, dep := range deps {
+		if dep != page {
+			others = append(others, dep)
+		}
+	}
+
+	assemble := func() (*template.Template, error) {
+		tpl := template.New(page.Name()).Funcs(funcs)
+		if err := addParseTrees(tpl, page.trees, page.Name()); err != nil {
+			return nil, err
+		}
+
+		for _, dep := range others {
+			names := dep.defines()
+			if len(names) == 0 {
+				names = []string{dep.Name()}
+			}
 
-	tpl, err := template.New(page.Name()).Funcs(funcs).Parse(page.Content())
+			if err := addParseTrees(tpl, dep.trees, names...); err != nil {
+				return nil, err
+			}
+		}
+
+		// html/template keeps the tree of the receiver, the page tree added
+		// by AddParseTree is only visible through the lookup.
+		tpl = tpl.Lookup(page.Name())
+		bindFuncs(tpl, funcs)
+		return tpl, nil
+	}
+
+	tpl, err := assemble()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, item := range data {
-		if item == page {
-			continue
+	c := &compiled{
+		tpl:      tpl,
+		assemble: assemble,
+		funcs:    funcs,
+		aliases:  t.deprecatedFuncs,
+		size:     size * compiledSizeFactor,
+		info:     statDeps(page, deps),
+	}
+
+	buffered := slices.Concat(boundAssetsFuncs(funcs), boundPlaceholderFuncs(funcs))
+	tagged := boundTagsFuncs(funcs)
+	for _, dep := range deps {
+		c.buffered = c.buffered || dep.calls(buffered...)
+		c.tagged = c.tagged || dep.calls(tagged...)
+	}
+
+	c.limits = t.Limits()
+	if c.limits.enabled() || c.buffered || c.tagged {
+		c.instances = &sync.Pool{}
+	}
+
+	return c, nil
+}
+
+// funcsSnapshot is the result of buildFuncs, valid for the generation of
+// the theme it was computed in.
+type funcsSnapshot struct {
+	gen   uint64
+	funcs template.FuncMap
+
+	// locales holds the funcs localized by buildLocaleFuncs.
+	locales sync.Map
+}
+
+// statDeps combines the info of the templates of a page: the hash covers
+// every template, the modification time is the latest one and the size is
+// the total.
+func statDeps(page *dependency, deps map[string]*dependency) TemplateInfo {
+	info := TemplateInfo{ContentType: Stat(page.Template).ContentType}
+
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(deps)) {
+		dep := Stat(deps[name].Template)
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(dep.Hash))
+		h.Write([]byte{0})
+
+		if dep.ModTime.After(info.ModTime) {
+			info.ModTime = dep.ModTime
 		}
+		info.Size += dep.Size
+	}
+	info.Hash = hex.EncodeToString(h.Sum(nil))
 
-		content := item.Content()
+	return info
+}
 
-		matches := defineRe.FindAllStringSubmatch(content, -1)
+// buildFuncs returns the functions a template set is built with. They are
+// computed once per reset and shared, read-only, by the sets built since.
+func (t *Theme) buildFuncs() template.FuncMap {
+	return t.funcsSnapshot().funcs
+}
 
-		if len(matches) == 0 {
-			if _, err = tpl.New(item.Name()).Funcs(funcs).Parse(content); err != nil {
-				return nil, err
-			}
+func (t *Theme) funcsSnapshot() *funcsSnapshot {
+	gen := t.gen.Load()
+	if snapshot := t.funcs.Load(); snapshot != nil && snapshot.gen == gen {
+		return snapshot
+	}
+
+	funcs := t.FuncMap()
+	if policy := t.TrustPolicy(); policy != nil {
+		maps.Copy(funcs, trustFuncs(policy, funcs))
+	}
+	maps.Copy(funcs, t.deprecatedFuncs(funcs))
+	if _, ok := funcs["include"]; !ok {
+		funcs["include"] = includeFunc(nil)
+	}
+	maps.Copy(funcs, defaultAssetsFuncs(funcs))
+	maps.Copy(funcs, defaultPlaceholderFuncs(funcs))
+	maps.Copy(funcs, defaultTagsFuncs(funcs))
+
+	snapshot := &funcsSnapshot{gen: gen, funcs: funcs}
+	t.funcs.Store(snapshot)
+	return snapshot
+}
+
+// buildLocaleFuncs returns the functions of buildFuncs localized for the
+// locale, computed once per reset and locale.
+func (t *Theme) buildLocaleFuncs(locale language.Tag) template.FuncMap {
+	snapshot := t.funcsSnapshot()
+
+	key := locale.String()
+	if funcs, ok := snapshot.locales.Load(key); ok {
+		return funcs.(template.FuncMap)
+	}
+
+	funcs, _ := snapshot.locales.LoadOrStore(key, localeFuncs(snapshot.funcs, locale))
+	return funcs.(template.FuncMap)
+}
+
+// bindFuncs binds the functions operating on the template set itself to tpl.
+// It must be called again on every clone of tpl.
+func bindFuncs(tpl *template.Template, funcs template.FuncMap) {
+	if _, ok := funcs["include"].(includeFn); ok {
+		tpl.Funcs(template.FuncMap{"include": includeFunc(tpl)})
+	}
+}
+
+// includeFn is the type of the include function, which executes the template
+// named by its first argument with the optional data of the second one.
+// Unlike {{template}}, the name may be computed at execution time.
+type includeFn func(name string, data ...any) (template.HTML, error)
+
+func includeFunc(tpl *template.Template) includeFn {
+	return func(name string, data ...any) (template.HTML, error) {
+		if tpl == nil {
+			return "", fmt.Errorf("include %q: template set is not bound", name)
+		}
+
+		var d any
+		if len(data) > 0 {
+			d = data[0]
+		}
+
+		var buf strings.Builder
+		if err := tpl.ExecuteTemplate(&buf, name, d); err != nil {
+			return "", &includeError{name: name, err: err}
+		}
+		return template.HTML(buf.String()), nil
+	}
+}
+
+// includeError records the template executed by a failed include, so the
+// inclusion stack of an ExecError follows the names computed at execution
+// time.
+type includeError struct {
+	name string
+	err  error
+}
+
+func (e *includeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *includeError) Unwrap() error {
+	return e.err
+}
+
+// dependency is a store template along with its parse trees.
+type dependency struct {
+	Template
+	*parsed
+}
+
+// parsed holds the parse trees of a source template, along with what builds
+// derive from them.
+type parsed struct {
+	trees map[string]*parse.Tree
+	size  int64
+
+	// defined, included and called are the sorted names of the templates
+	// declared and invoked by the trees, and of the functions they call.
+	defined  []string
+	included []string
+	called   []string
+}
+
+func newParsed(trees map[string]*parse.Tree) *parsed {
+	p := &parsed{trees: trees}
+
+	for name, tree := range trees {
+		if name != rootTree {
+			p.defined = append(p.defined, name)
+		}
+		walkTemplateNodes(tree.Root, func(node *parse.TemplateNode) {
+			p.included = append(p.included, node.Name)
+		})
+		walkIdentifiers(tree.Root, func(name string) {
+			p.called = append(p.called, name)
+		})
+	}
+
+	for _, names := range []*[]string{&p.defined, &p.included, &p.called} {
+		slices.Sort(*names)
+		*names = slices.Compact(*names)
+	}
+
+	return p
+}
+
+// defines returns the names of the templates declared by {{define}} or
+// {{block}}.
+func (d *dependency) defines() []string {
+	return d.defined
+}
+
+// includes returns the names of the templates invoked by {{template}} or
+// {{block}}. The parser only accepts constant names, so every dependency is
+// known statically.
+func (d *dependency) includes() []string {
+	return d.included
+}
+
+// calls reports whether the trees call one of the functions.
+func (d *dependency) calls(names ...string) bool {
+	return slices.ContainsFunc(names, func(name string) bool {
+		_, found := slices.BinarySearch(d.called, name)
+		return found
+	})
+}
+
+// addParseTrees adds the parse trees of a store template to tpl. The
+// top-level tree is registered under each of the given names, the {{define}}
+// trees under their own names.
+//
+// Every tree is copied before use because html/template rewrites the trees
+// it escapes.
+func addParseTrees(tpl *template.Template, trees map[string]*parse.Tree, names ...string) error {
+	for name, tree := range trees {
+		if name == rootTree {
 			continue
 		}
+		if err := addParseTree(tpl, name, tree); err != nil {
+			return err
+		}
+	}
 
-		for _, m := range matches {
-			if len(m) > 1 {
-				if _, err = tpl.New(m[1]).Funcs(funcs).Parse(content); err != nil {
-					return nil, err
-				}
-			}
+	for _, name := range names {
+		if err := addParseTree(tpl, name, trees[rootTree]); err != nil {
+			return err
 		}
 	}
 
-	return tpl, nil
+	return nil
 }
 
-func (t *Theme) findByName(ctx context.Context, data map[string]Template, name string) error {
-	if _, ok := data[name]; ok {
+// parseTrees parses the source template into its top-level and {{define}}
+// trees.
+//
+// Parse trees are cached by source name and content hash, so a layout shared
+// by many pages is parsed only once.
+func (t *Theme) parseTrees(source Template, funcs template.FuncMap) (*parsed, error) {
+	debug := t.debug.Load()
+
+	h := sha256.New()
+	h.Write([]byte(source.Name()))
+	h.Write([]byte{0})
+	h.Write([]byte(source.Content()))
+	key := [sha256.Size]byte(h.Sum(nil))
+
+	if !debug {
+		if p, ok := t.trees.Load(string(key[:])); ok {
+			return p, nil
+		}
+	}
+
+	// parsing only checks that functions exist, so the func map is passed
+	// as is instead of converting it into a text template for every source
+	trees, err := parse.Parse(rootTree, source.Content(), "", "", funcs, parseBuiltins)
+	if err != nil {
+		err = errors.New(strings.ReplaceAll(err.Error(), rootTree, source.Name()))
+		return nil, newParseError(source, err, debug)
+	}
+
+	for _, tree := range trees {
+		// error messages locate nodes by the parse name of their tree
+		tree.ParseName = source.Name()
+	}
+
+	p := newParsed(trees)
+	p.size = int64(len(source.Content())) * parsedSizeFactor
+	if !debug {
+		t.trees.Store(string(key[:]), p)
+	}
+
+	return p, nil
+}
+
+func addParseTree(tpl *template.Template, name string, tree *parse.Tree) error {
+	if tree == nil {
+		return nil
+	}
+
+	// text/template keeps a defined template over an empty redefinition,
+	// but html/template would still register the empty one for lookups
+	if existing := tpl.Lookup(name); existing != nil && existing.Tree != nil && parse.IsEmptyTree(tree.Root) {
+		return nil
+	}
+
+	tree = tree.Copy()
+	tree.Name = name
+
+	_, err := tpl.AddParseTree(name, tree)
+	return err
+}
+
+func (t *Theme) findByName(ctx context.Context, deps map[string]*dependency, fetched map[string]Template, funcs template.FuncMap, name string) error {
+	if _, ok := deps[name]; ok {
 		return nil
 	}
 
-	dep, err := t.find(ctx, name)
+	item, source, err := t.findVariant(ctx, fetched, name)
 	if err != nil {
 		return err
 	}
 
-	data[name] = dep
+	p, err := t.parseTrees(source, funcs)
+	if err != nil {
+		return err
+	}
+
+	dep := &dependency{Template: item, parsed: p}
+	deps[name] = dep
 
-	if err = t.findByTemplate(ctx, data, dep); err != nil {
+	if err = t.findByTemplate(ctx, deps, fetched, funcs, dep); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (t *Theme) findByTemplate(ctx context.Context, data map[string]Template, item Template) error {
-	if item.Path() != item.Name() {
-		if err := t.findByName(ctx, data, item.Path()); err != nil {
+func (t *Theme) findByTemplate(ctx context.Context, deps map[string]*dependency, fetched map[string]Template, funcs template.FuncMap, dep *dependency) error {
+	includes := dep.includes()
+	if dep.Path() != dep.Name() {
+		t.prefetch(ctx, deps, fetched, append([]string{dep.Path()}, includes...))
+
+		if err := t.findByName(ctx, deps, fetched, funcs, dep.Path()); err != nil {
+			return err
+		}
+	} else {
+		t.prefetch(ctx, deps, fetched, includes)
+	}
+
+	for _, name := range includes {
+		if err := t.findByName(ctx, deps, fetched, funcs, name); err != nil {
+			if !errors.Is(err, ErrTemplateNotFound) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findIncluded adds the templates of the theme that are included into every
+// page, either all of them in eager mode or the ones matching AutoInclude.
+func (t *Theme) findIncluded(ctx context.Context, deps map[string]*dependency, fetched map[string]Template, funcs template.FuncMap) error {
+	eager := t.eager.Load()
+	patterns := t.AutoInclude()
+
+	var names []string
+	if eager || slices.ContainsFunc(patterns, hasMeta) {
+		var err error
+		if names, err = t.list(ctx); err != nil {
 			return err
 		}
 	}
 
-	matches := templateRe.FindAllStringSubmatch(item.Content(), -1)
-	for _, match := range matches {
-		if len(match) > 2 {
-			if err := t.findByName(ctx, data, match[2]); err != nil {
-				if !errors.Is(err, ErrTemplateNotFound) {
-					return err
-				}
+	if eager {
+		t.prefetch(ctx, deps, fetched, names)
+
+		for _, name := range names {
+			if err := t.findByName(ctx, deps, fetched, funcs, name); err != nil && !errors.Is(err, ErrTemplateNotFound) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var matches []string
+	for _, pattern := range patterns {
+		if !hasMeta(pattern) {
+			if err := t.findByName(ctx, deps, fetched, funcs, pattern); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, name := range names {
+			matched, err := path.Match(pattern, name)
+			if err != nil {
+				return fmt.Errorf("theme: invalid auto include pattern %q: %w", pattern, err)
 			}
+			if matched {
+				matches = append(matches, name)
+			}
+		}
+	}
+
+	t.prefetch(ctx, deps, fetched, matches)
+
+	for _, name := range matches {
+		if err := t.findByName(ctx, deps, fetched, funcs, name); err != nil && !errors.Is(err, ErrTemplateNotFound) {
+			return err
 		}
 	}
 
 	return nil
 }
 
+func hasMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// list returns the names of the templates of the theme and its parents,
+// whose stores must all implement Lister.
+func (t *Theme) list(ctx context.Context) ([]string, error) {
+	lister, ok := t.store.(Lister)
+	if !ok {
+		return nil, fmt.Errorf("theme: failed to list templates of %s: %w", t.name, ErrNotLister)
+	}
+
+	names, err := lister.List(ctx, t.name)
+	if err != nil {
+		return nil, fmt.Errorf("theme: failed to list templates of %s: %w", t.name, err)
+	}
+
+	if parent := t.Parent(); parent != nil {
+		items, err := parent.list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, items...)
+	}
+
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+// prefetch fetches the templates of names not found yet into fetched, with
+// a single request per theme of the hierarchy whose store implements
+// BatchFinder. It stops at the first store that doesn't, or fails, leaving
+// the remaining templates to be found one by one, which reports errors.
+// Templates missing from every store of the hierarchy are fetched as nil.
+func (t *Theme) prefetch(ctx context.Context, deps map[string]*dependency, fetched map[string]Template, names []string) {
+	if _, ok := t.store.(BatchFinder); !ok {
+		return
+	}
+
+	variant := VariantFrom(ctx)
+
+	var pending []string
+	for _, name := range names {
+		if _, ok := deps[name]; ok {
+			continue
+		}
+		if variant != "" {
+			pending = append(pending, variantName(name, variant))
+		}
+		pending = append(pending, name)
+	}
+	pending = slices.DeleteFunc(pending, func(name string) bool {
+		_, ok := fetched[name]
+		return ok
+	})
+	slices.Sort(pending)
+	pending = slices.Compact(pending)
+
+	for level := t; level != nil && len(pending) > 0; level = level.Parent() {
+		batcher, ok := level.store.(BatchFinder)
+		if !ok {
+			return
+		}
+
+		start := time.Now()
+		items, err := batcher.FindMany(ctx, level.name, pending)
+		if err != nil {
+			return
+		}
+		duration := time.Since(start)
+
+		pending = slices.DeleteFunc(pending, func(name string) bool {
+			item, ok := items[name]
+			if ok {
+				fetched[name] = item
+				t.emit(ctx, Event{Kind: EventStoreFetch, Template: name, Duration: duration, Owner: level.name})
+			}
+			return ok
+		})
+	}
+
+	for _, name := range pending {
+		fetched[name] = nil
+	}
+}
+
+// findFetched returns the named template from fetched, or finds it.
+func (t *Theme) findFetched(ctx context.Context, fetched map[string]Template, name string) (Template, error) {
+	item, ok := fetched[name]
+	if !ok {
+		return t.find(ctx, name)
+	}
+	if item != nil {
+		return item, nil
+	}
+
+	// missing from the hierarchy, only the built-in components remain
+	item, _, err := builtin.lookup(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
+	}
+	return item, nil
+}
+
+// find returns the named template from the theme or the closest ancestor
+// owning it.
+//
+// Outside debug mode, the owner of every name, or its absence from the whole
+// hierarchy, is memoized until the theme is reset, so cold builds don't walk
+// every level again.
+//
+// Each find is reported to the event sinks as a store fetch.
 func (t *Theme) find(ctx context.Context, name string) (Template, error) {
+	if !t.observed() {
+		return t.findOwner(ctx, name)
+	}
+
+	start := time.Now()
+	item, err := t.findOwner(ctx, name)
+
+	event := Event{Kind: EventStoreFetch, Template: name, Duration: time.Since(start), Err: err}
+	if item != nil {
+		event.Owner = item.Theme()
+	}
+	t.emit(ctx, event)
+
+	return item, err
+}
+
+func (t *Theme) findOwner(ctx context.Context, name string) (Template, error) {
+	debug := t.debug.Load()
+
+	if !debug {
+		if owner, ok := t.owners.Load(name); ok {
+			if owner == nil {
+				return nil, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, ErrTemplateNotFound)
+			}
+
+			item, err := owner.store.Find(ctx, owner.name, name)
+			if err == nil {
+				return item, nil
+			}
+			if !errors.Is(err, ErrTemplateNotFound) {
+				return nil, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
+			}
+
+			// the owner lost the template, walk the hierarchy again
+			t.owners.Delete(name)
+		}
+	}
+
+	item, owner, err := t.lookup(ctx, name)
+
+	if !debug {
+		if err == nil {
+			if owner != nil {
+				t.owners.Store(name, owner)
+			}
+		} else if errors.Is(err, ErrTemplateNotFound) && !errors.Is(err, ErrCircuitOpen) {
+			t.owners.Store(name, (*Theme)(nil))
+		}
+	}
+
+	return item, err
+}
+
+// lookup returns the template and the theme owning it. The owner is nil
+// when a store of the hierarchy was unavailable, see StoreBreaker, as the
+// template may then not be the one of the actual owner.
+func (t *Theme) lookup(ctx context.Context, name string) (Template, *Theme, error) {
 	item, err := t.store.Find(ctx, t.name, name)
 	if err == nil {
-		return item, nil
+		return item, t, nil
 	}
 
 	if errors.Is(err, ErrTemplateNotFound) {
-		if parent := t.parent.Load(); parent != nil {
-			item, err1 := parent.find(ctx, name)
-			if err1 == nil {
-				return item, nil
+		var (
+			owner *Theme
+			err1  error
+		)
+		if parent := t.Parent(); parent != nil {
+			item, owner, err1 = parent.lookup(ctx, name)
+			if err1 != nil {
+				err = errors.Join(err, err1)
+			}
+		} else if t != builtin {
+			item, owner, err1 = builtin.lookup(ctx, name)
+		} else {
+			err1 = err
+		}
+
+		if err1 == nil {
+			if errors.Is(err, ErrCircuitOpen) {
+				owner = nil
 			}
-			err = errors.Join(err, err1)
+			return item, owner, nil
 		}
 	}
 
-	return nil, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
+	return nil, nil, fmt.Errorf("theme: failed to find template %s/%s: %w", t.name, name, err)
 }
@@ -0,0 +1,80 @@
+package got
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// benchTheme returns a theme whose page extends a layout and includes
+// partials, with the full function set.
+func benchTheme(partials int) *Theme {
+	store := NewStoreMemory()
+	store.Add("bench", "layouts/base", `<!DOCTYPE html><html><head><title>{{.Title}}</title></head>`+
+		`<body>{{template "header" .}}{{block "content" .}}{{end}}{{template "footer" .}}</body></html>`)
+	store.Add("bench", "header", `<header>{{range .Menu}}<a href="{{.URL}}">{{.Title}}</a>{{end}}</header>`)
+	store.Add("bench", "footer", `<footer>{{.Title | str_upper}} {{now.Year}}</footer>`)
+
+	content := `<!-- layouts/base -->{{define "content"}}`
+	for i := range partials {
+		name := fmt.Sprintf("partials/p%d", i)
+		store.Add("bench", name, fmt.Sprintf(`<section>{{if .Title}}<h2>{{.Title}} %d</h2>{{end}}{{range .Items}}<p>{{.}}</p>{{end}}</section>`, i))
+		content += fmt.Sprintf(`{{template %q .}}`, name)
+	}
+	content += `{{end}}`
+	store.Add("bench", "page", content)
+
+	theme := NewTheme("bench", store)
+	theme.SetFuncMap(Funcs)
+	return theme
+}
+
+// BenchmarkTheme_BuildCold measures a build after Clear, including parsing.
+func BenchmarkTheme_BuildCold(b *testing.B) {
+	theme := benchTheme(10)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		theme.Clear()
+		if _, err := theme.compile(ctx, "page"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTheme_BuildParsed measures a build whose parse trees are cached,
+// as after an eviction.
+func BenchmarkTheme_BuildParsed(b *testing.B) {
+	theme := benchTheme(10)
+	ctx := context.Background()
+
+	if _, err := theme.build(ctx, "page"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := theme.build(ctx, "page"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTheme_Write(b *testing.B) {
+	theme := benchTheme(10)
+	ctx := context.Background()
+	data := map[string]any{
+		"Title": "Bench",
+		"Menu":  []map[string]string{{"URL": "/", "Title": "Home"}, {"URL": "/about", "Title": "About"}},
+		"Items": []string{"a", "b", "c"},
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := theme.Write(ctx, io.Discard, "page", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -2,10 +2,15 @@ package got
 
 import (
 	"context"
+	"errors"
 	"html/template"
+	"io"
+	"runtime/pprof"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -486,3 +491,545 @@ func TestTheme_WithComplexData(t *testing.T) {
 
 	mockStore.AssertExpectations(t)
 }
+
+func TestTheme_Write_SharesParseTrees(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", `<main>{{block "content" .}}default{{end}}</main>`)
+	store.Add("test", "page/a", `<!-- layouts/base -->{{define "content"}}<a>{{.}}</a>{{end}}`)
+	store.Add("test", "page/b", `<!-- layouts/base -->{{define "content"}}<b>{{.}}</b>{{end}}`)
+
+	theme := NewTheme("test", store)
+	ctx := context.Background()
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page/a", "<x>"))
+	assert.Equal(t, "<main><a>&lt;x&gt;</a></main>", buf.String())
+
+	buf.Reset()
+	require.NoError(t, theme.Write(ctx, &buf, "page/b", "<y>"))
+	assert.Equal(t, "<main><b>&lt;y&gt;</b></main>", buf.String())
+
+	buf.Reset()
+	require.NoError(t, theme.Write(ctx, &buf, "layouts/base", nil))
+	assert.Equal(t, "<main>default</main>", buf.String())
+
+	assert.Equal(t, 3, theme.trees.Len())
+
+	theme.Clear()
+	assert.Equal(t, 0, theme.trees.Len(), "parse tree cache should be empty after Clear")
+}
+
+func TestTheme_CacheBudget(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "a", `<p>{{.}}</p>`)
+	store.Add("test", "b", `<div>{{.}}</div>`)
+
+	theme := NewTheme("test", store)
+	ctx := context.Background()
+
+	assert.Equal(t, int64(0), theme.CacheSize())
+	assert.Equal(t, int64(0), theme.CacheBudget())
+
+	require.NoError(t, theme.Write(ctx, io.Discard, "a", nil))
+	sizeA := theme.CacheSize()
+	assert.Positive(t, sizeA)
+
+	require.NoError(t, theme.Write(ctx, io.Discard, "b", nil))
+	assert.Greater(t, theme.CacheSize(), sizeA)
+
+	theme.SetCacheBudget(sizeA)
+	assert.Equal(t, sizeA, theme.CacheBudget())
+	assert.LessOrEqual(t, theme.CacheSize(), sizeA)
+
+	theme.Clear()
+	assert.Equal(t, int64(0), theme.CacheSize())
+	assert.Equal(t, sizeA, theme.CacheBudget())
+}
+
+func TestTheme_Compile_CanceledCaller(t *testing.T) {
+	store := &slowStore{StoreMemory: NewStoreMemory(), release: make(chan struct{})}
+	store.Add("test", "page", `<p>{{.}}</p>`)
+
+	theme := NewTheme("test", store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		first <- theme.Write(ctx, io.Discard, "page", nil)
+	}()
+	store.waitCalls(t, 1)
+	cancel()
+
+	// the build outlives the first caller, the second one shares it
+	second := make(chan error, 1)
+	go func() {
+		var out strings.Builder
+		err := theme.Write(context.Background(), &out, "page", "ok")
+		assert.Equal(t, "<p>ok</p>", out.String())
+		second <- err
+	}()
+	close(store.release)
+	assert.NoError(t, <-second)
+	<-first
+	assert.Equal(t, int32(1), store.calls.Load())
+}
+
+func TestTheme_UseCacheBudget(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreMemory()
+	store.Add("a", "layouts/base", `<main>{{block "content" .}}{{end}}</main>`)
+	store.Add("a", "page", `<!-- layouts/base -->{{define "content"}}<p>{{.}}</p>{{end}}`)
+	store.Add("b", "page", `<div>{{.}}</div>`)
+
+	a := NewTheme("a", store)
+	b := NewTheme("b", store)
+
+	require.NoError(t, a.Write(ctx, io.Discard, "page", nil))
+	require.Positive(t, a.CacheSize())
+
+	budget := NewCacheBudget(0)
+	a.UseCacheBudget(budget)
+	b.UseCacheBudget(budget)
+	assert.Equal(t, int64(0), a.CacheSize())
+
+	require.NoError(t, a.Write(ctx, io.Discard, "page", nil))
+	sizeA := a.CacheSize()
+	// parse trees and owners are accounted with the compiled templates
+	assert.Equal(t, 2, a.trees.Len())
+	assert.Greater(t, sizeA, a.cache.Size())
+	assert.Equal(t, sizeA, budget.Size())
+
+	require.NoError(t, b.Write(ctx, io.Discard, "page", nil))
+	assert.Equal(t, sizeA+b.CacheSize(), budget.Size())
+
+	// the budget is shared: limiting b evicts the least recently used
+	// entries, those of a
+	b.SetCacheBudget(b.CacheSize())
+	assert.Equal(t, b.CacheSize(), a.CacheBudget())
+	assert.Equal(t, int64(0), a.CacheSize())
+	assert.Equal(t, b.CacheSize(), budget.Size())
+}
+
+func TestTheme_Compiled(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base", `<main>{{block "content" .}}default{{end}}</main>`)
+	store.Add("test", "page", `<!-- layouts/base -->{{define "content"}}<p>{{.}}</p>{{end}}`)
+
+	theme := NewTheme("test", store)
+	ctx := context.Background()
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", "cached"))
+	assert.Equal(t, "<main><p>cached</p></main>", buf.String())
+
+	tpl, err := theme.Compiled(ctx, "page")
+	require.NoError(t, err)
+
+	_, err = tpl.New("extra").Funcs(template.FuncMap{"shout": strings.ToUpper}).Parse(`{{shout .}}`)
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, tpl.ExecuteTemplate(&buf, "content", "direct"))
+	assert.Equal(t, "<p>direct</p>", buf.String())
+
+	buf.Reset()
+	require.NoError(t, tpl.ExecuteTemplate(&buf, "extra", "hi"))
+	assert.Equal(t, "HI", buf.String())
+
+	buf.Reset()
+	require.NoError(t, theme.Write(ctx, &buf, "page", "again"))
+	assert.Equal(t, "<main><p>again</p></main>", buf.String(), "clone must not affect the cached template")
+
+	other, err := theme.Compiled(ctx, "page")
+	require.NoError(t, err)
+	assert.Nil(t, other.Lookup("extra"))
+}
+
+func TestTheme_Compiled_NotFound(t *testing.T) {
+	theme := NewTheme("test", NewStoreMemory())
+
+	_, err := theme.Compiled(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestTheme_Write_ProfilerLabels(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{probe}}`)
+
+	var profile strings.Builder
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(template.FuncMap{
+		"probe": func() string {
+			_ = pprof.Lookup("goroutine").WriteTo(&profile, 1)
+			return ""
+		},
+	})
+
+	require.NoError(t, theme.Write(context.Background(), io.Discard, "page", nil))
+	assert.Contains(t, profile.String(), `"template":"page"`)
+	assert.Contains(t, profile.String(), `"theme":"test"`)
+}
+
+func TestDependency_DefinesAndIncludes(t *testing.T) {
+	theme := NewTheme("test", &MockStore{})
+	item := createTestTemplate("test", "page", `{{/* {{template "commented"}} */}}
+{{define "content"}}{{if .}}{{template "partials/a" .}}{{else}}{{template "partials/b"}}{{end}}{{end}}
+{{define "sidebar"}}{{range .}}{{block "item" .}}{{.}}{{end}}{{end}}{{template "partials/a"}}{{end}}`)
+
+	p, err := theme.parseTrees(item, nil)
+	require.NoError(t, err)
+
+	dep := &dependency{Template: item, parsed: p}
+	assert.Equal(t, []string{"content", "item", "sidebar"}, dep.defines())
+	assert.Equal(t, []string{"item", "partials/a", "partials/b"}, dep.includes())
+}
+
+func TestTheme_Write_IgnoresCommentedDependencies(t *testing.T) {
+	mockStore := &MockStore{}
+	theme := NewTheme("test", mockStore)
+
+	ctx := context.Background()
+	page := createTestTemplate("test", "page", `{{/* {{template "partials/old" .}} */}}<p>{{"define \"x\""}}</p>`)
+
+	mockStore.On("Find", ctx, "test", "page").Return(page, nil).Once()
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", nil))
+	assert.Equal(t, `<p>define &#34;x&#34;</p>`, buf.String())
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestTheme_SetEager(t *testing.T) {
+	parentStore := NewStoreMemory()
+	parentStore.Add("parent", "widgets/quote", `<q>{{.}}</q>`)
+
+	store := NewStoreMemory()
+	store.Add("child", "page", `{{range .}}{{include (printf "widgets/%s" .Kind) .Value}}{{end}}`)
+	store.Add("child", "widgets/text", `<p>{{.}}</p>`)
+
+	parent := NewTheme("parent", parentStore)
+	theme := NewTheme("child", store)
+	theme.SetParent(parent)
+
+	ctx := context.Background()
+	data := []map[string]string{
+		{"Kind": "text", "Value": "<hello>"},
+		{"Kind": "quote", "Value": "world"},
+	}
+
+	assert.False(t, theme.Eager())
+	err := theme.Write(ctx, io.Discard, "page", data)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"widgets/text" is undefined`)
+
+	theme.SetEager(true)
+	assert.True(t, theme.Eager())
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", data))
+	assert.Equal(t, "<p>&lt;hello&gt;</p><q>world</q>", buf.String())
+
+	tpl, err := theme.Compiled(ctx, "page")
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, tpl.Execute(&buf, data[:1]))
+	assert.Equal(t, "<p>&lt;hello&gt;</p>", buf.String())
+}
+
+func TestTheme_Include_UserDefined(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{include "x"}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(template.FuncMap{"include": func(s string) string { return "custom:" + s }})
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(context.Background(), &buf, "page", nil))
+	assert.Equal(t, "custom:x", buf.String())
+}
+
+func TestTheme_SetAutoInclude(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "components/button", `{{define "button"}}<button>{{.}}</button>{{end}}`)
+	store.Add("test", "components/card", `{{define "card"}}<div>{{template "button" .}}</div>{{end}}`)
+	store.Add("test", "macros", `{{define "upper"}}{{str_upper .}}{{end}}`)
+	store.Add("test", "page", `{{template "card" "ok"}}{{template "upper" "x"}}`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+	ctx := context.Background()
+
+	assert.Nil(t, theme.AutoInclude())
+
+	err := theme.Write(ctx, io.Discard, "page", nil)
+	require.Error(t, err)
+
+	theme.SetAutoInclude("components/*", "macros")
+	assert.Equal(t, []string{"components/*", "macros"}, theme.AutoInclude())
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(ctx, &buf, "page", nil))
+	assert.Equal(t, "<div><button>ok</button></div>X", buf.String())
+
+	theme.SetAutoInclude("missing")
+	err = theme.Write(ctx, io.Discard, "page", nil)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+
+	theme.SetAutoInclude("[")
+	err = theme.Write(ctx, io.Discard, "page", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid auto include pattern")
+}
+
+func TestTheme_List_NotLister(t *testing.T) {
+	ctx := context.Background()
+	store := NewStoreKV(func(_ context.Context, key string) ([]byte, error) {
+		return map[string][]byte{"test/page": []byte("page")}[key], nil
+	}, nil)
+
+	theme := NewTheme("test", store)
+	require.NoError(t, theme.Write(ctx, io.Discard, "page", nil))
+
+	// listings the store can't give fail instead of including nothing
+	theme.SetAutoInclude("components/*")
+	err := theme.Write(ctx, io.Discard, "page", nil)
+	assert.ErrorIs(t, err, ErrNotLister)
+	assert.ErrorContains(t, err, "theme: failed to list templates of test")
+
+	theme.SetAutoInclude()
+	theme.SetEager(true)
+	assert.ErrorIs(t, theme.Write(ctx, io.Discard, "page", nil), ErrNotLister)
+
+	// parents must list too
+	child := NewTheme("child", NewStoreMemory())
+	child.SetParent(theme)
+	child.SetEager(true)
+	assert.ErrorIs(t, child.Write(ctx, io.Discard, "page", nil), ErrNotLister)
+}
+
+func TestTheme_Find_MemoizesOwners(t *testing.T) {
+	grandStore := &MockStore{}
+	parentStore := &MockStore{}
+	childStore := &MockStore{}
+
+	grand := NewTheme("grand", grandStore)
+	parent := NewTheme("parent", parentStore)
+	parent.SetParent(grand)
+	child := NewTheme("child", childStore)
+	child.SetParent(parent)
+
+	ctx := context.Background()
+	layout := createTestTemplate("grand", "layout", `<main>{{block "content" .}}{{end}}</main>`)
+	pageA := createTestTemplate("child", "a", `<!-- layout -->{{define "content"}}a{{end}}`)
+	pageB := createTestTemplate("child", "b", `<!-- layout -->{{define "content"}}b{{end}}`)
+
+	childStore.On("Find", ctx, "child", "a").Return(pageA, nil).Once()
+	childStore.On("Find", ctx, "child", "b").Return(pageB, nil).Once()
+
+	// the first build walks the hierarchy, the second one asks the owner directly
+	childStore.On("Find", ctx, "child", "layout").Return(nil, ErrTemplateNotFound).Once()
+	parentStore.On("Find", ctx, "parent", "layout").Return(nil, ErrTemplateNotFound).Once()
+	grandStore.On("Find", ctx, "grand", "layout").Return(layout, nil).Twice()
+
+	// missing templates are looked up once in every level
+	childStore.On("Find", ctx, "child", "content").Return(nil, ErrTemplateNotFound).Once()
+	parentStore.On("Find", ctx, "parent", "content").Return(nil, ErrTemplateNotFound).Once()
+	grandStore.On("Find", ctx, "grand", "content").Return(nil, ErrTemplateNotFound).Once()
+
+	var buf strings.Builder
+	require.NoError(t, child.Write(ctx, &buf, "a", nil))
+	assert.Equal(t, "<main>a</main>", buf.String())
+
+	buf.Reset()
+	require.NoError(t, child.Write(ctx, &buf, "b", nil))
+	assert.Equal(t, "<main>b</main>", buf.String())
+
+	grandStore.AssertExpectations(t)
+	parentStore.AssertExpectations(t)
+	childStore.AssertExpectations(t)
+
+	// Clear forgets the owners
+	child.Clear()
+	childStore.On("Find", ctx, "child", "layout").Return(nil, ErrTemplateNotFound).Once()
+	parentStore.On("Find", ctx, "parent", "layout").Return(nil, ErrTemplateNotFound).Once()
+	grandStore.On("Find", ctx, "grand", "layout").Return(layout, nil).Once()
+
+	_, err := child.find(ctx, "layout")
+	require.NoError(t, err)
+
+	grandStore.AssertExpectations(t)
+	parentStore.AssertExpectations(t)
+	childStore.AssertExpectations(t)
+}
+
+func TestTheme_ParseBuiltins(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `{{and 1 1}}{{or 0 1}}{{not false}}{{len "ab"}}{{index (slice "ab" 1) 0}}`+
+		`{{html "<"}}{{js "'"}}{{urlquery "a b"}}{{print 1}}{{printf "%d" 2}}{{println}}`+
+		`{{eq 1 1}}{{ne 1 2}}{{lt 1 2}}{{le 1 1}}{{gt 2 1}}{{ge 1 1}}{{call .F}}`)
+
+	var buf strings.Builder
+	err := NewTheme("test", store).Write(context.Background(), &buf, "page", map[string]any{"F": func() int { return 3 }})
+	require.NoError(t, err)
+	assert.NotEmpty(t, buf.String())
+}
+
+// batchStore is a memory store implementing BatchFinder, recording its
+// requests.
+type batchStore struct {
+	*StoreMemory
+	mu      sync.Mutex
+	batches [][]string
+	finds   []string
+}
+
+func (s *batchStore) Find(ctx context.Context, theme, name string) (Template, error) {
+	s.mu.Lock()
+	s.finds = append(s.finds, theme+"/"+name)
+	s.mu.Unlock()
+	return s.StoreMemory.Find(ctx, theme, name)
+}
+
+func (s *batchStore) FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error) {
+	s.mu.Lock()
+	s.batches = append(s.batches, slices.Concat([]string{theme}, names))
+	s.mu.Unlock()
+
+	items := make(map[string]Template)
+	for _, name := range names {
+		if item, err := s.StoreMemory.Find(ctx, theme, name); err == nil {
+			items[name] = item
+		}
+	}
+	return items, nil
+}
+
+func TestTheme_BatchFinder(t *testing.T) {
+	ctx := context.Background()
+
+	store := &batchStore{StoreMemory: NewStoreMemory()}
+	store.Add("base", "layout", `<main>{{block "content" .}}{{end}}</main>{{template "footer"}}`)
+	store.Add("base", "footer", `<footer></footer>`)
+	store.Add("base", "header", `<header></header>`)
+	store.Add("child", "page", `<!-- layout -->{{define "content"}}{{template "header"}}{{template "nav"}}{{end}}`)
+	store.Add("child", "nav", `<nav></nav>`)
+
+	parent := NewTheme("base", store)
+	child := NewTheme("child", store)
+	child.SetParent(parent)
+
+	var buf strings.Builder
+	require.NoError(t, child.Write(ctx, &buf, "page", nil))
+	assert.Equal(t, "<main><header></header><nav></nav></main><footer></footer>", buf.String())
+
+	assert.Equal(t, [][]string{
+		{"child", "page"},
+		{"child", "header", "layout", "nav"},
+		{"base", "header", "layout"},
+		{"child", "content", "footer"},
+		{"base", "content", "footer"},
+	}, store.batches)
+	assert.Empty(t, store.finds)
+
+	t.Run("variant", func(t *testing.T) {
+		store.Add("child", "page.amp", `<!-- layout -->{{define "content"}}amp{{end}}`)
+		store.batches = nil
+
+		buf.Reset()
+		require.NoError(t, child.Write(WithVariant(ctx, "amp"), &buf, "page", nil))
+		assert.Equal(t, "<main>amp</main><footer></footer>", buf.String())
+		assert.Equal(t, []string{"child", "page", "page.amp"}, store.batches[0])
+	})
+
+	t.Run("built-in component", func(t *testing.T) {
+		store.Add("child", "list", `{{template "components/pagination.html" (paginate 30 10 2)}}`)
+		store.batches = nil
+
+		theme := NewTheme("child", store)
+		theme.SetParent(parent)
+		theme.SetFuncMap(Funcs)
+
+		buf.Reset()
+		require.NoError(t, theme.Write(ctx, &buf, "list", nil))
+		assert.Contains(t, buf.String(), `<nav class="pagination"`)
+		assert.Equal(t, [][]string{{"child", "list"}, {"child", "components/pagination.html"}, {"base", "components/pagination.html"}}, store.batches)
+		assert.Empty(t, store.finds)
+	})
+
+	t.Run("through a wrapper", func(t *testing.T) {
+		store.batches = nil
+		wrapped := NewStoreRetry(NewStoreCoalesce(store), RetryOptions{})
+
+		parent := NewTheme("base", wrapped)
+		child := NewTheme("child", wrapped)
+		child.SetParent(parent)
+
+		buf.Reset()
+		require.NoError(t, child.Write(ctx, &buf, "page", nil))
+		assert.Equal(t, "<main><header></header><nav></nav></main><footer></footer>", buf.String())
+		assert.Equal(t, [][]string{
+			{"child", "page"},
+			{"child", "header", "layout", "nav"},
+			{"base", "header", "layout"},
+			{"child", "content", "footer"},
+			{"base", "content", "footer"},
+		}, store.batches)
+		assert.Empty(t, store.finds)
+	})
+
+	t.Run("failed batch", func(t *testing.T) {
+		failing := &MockBatchStore{}
+		failing.On("FindMany", ctx, "test", []string{"page"}).Return(nil, errors.New("timeout")).Once()
+		failing.On("Find", ctx, "test", "page").Return(nil, errors.New("timeout")).Once()
+
+		err := NewTheme("test", failing).Write(ctx, &buf, "page", nil)
+		assert.ErrorContains(t, err, "timeout")
+		failing.AssertExpectations(t)
+	})
+}
+
+// MockBatchStore is a mock implementation of the BatchFinder interface
+type MockBatchStore struct {
+	MockStore
+}
+
+func (m *MockBatchStore) FindMany(ctx context.Context, theme string, names []string) (map[string]Template, error) {
+	args := m.Called(ctx, theme, names)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]Template), args.Error(1)
+}
+
+func TestTheme_Stat(t *testing.T) {
+	ctx := context.Background()
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	store := NewStoreMemory()
+	require.NoError(t, store.Save(ctx, &tmpl{theme: "test", name: "layout", path: "layout", content: "<main>{{block \"content\" .}}{{end}}</main>", info: TemplateInfo{ModTime: newer}}))
+	require.NoError(t, store.Save(ctx, &tmpl{theme: "test", name: "page.html", path: "layout", content: "{{define \"content\"}}page{{end}}", info: TemplateInfo{ModTime: older}}))
+
+	theme := NewTheme("test", store)
+
+	info, err := theme.Stat(ctx, "page.html")
+	require.NoError(t, err)
+	assert.Equal(t, newer, info.ModTime)
+	assert.Equal(t, int64(len(`<main>{{block "content" .}}{{end}}</main>{{define "content"}}page{{end}}`)), info.Size)
+	assert.Equal(t, "text/html; charset=utf-8", info.ContentType)
+	assert.Len(t, info.Hash, 64)
+
+	// the hash changes with any template
+	require.NoError(t, store.Save(ctx, &tmpl{theme: "test", name: "layout", path: "layout", content: "<div>{{block \"content\" .}}{{end}}</div>"}))
+	theme.Clear()
+
+	changed, err := theme.Stat(ctx, "page.html")
+	require.NoError(t, err)
+	assert.NotEqual(t, info.Hash, changed.Hash)
+	assert.Equal(t, older, changed.ModTime)
+
+	_, err = theme.Stat(ctx, "missing")
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
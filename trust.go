@@ -0,0 +1,105 @@
+package got
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+)
+
+var ErrUntrustedContent = errors.New("untrusted content")
+
+// TrustKind is the kind of content a template marks as trusted.
+type TrustKind string
+
+const (
+	TrustHTML     TrustKind = "to_html"
+	TrustHTMLAttr TrustKind = "to_html_attr"
+	TrustJS       TrustKind = "to_js"
+	TrustCSS      TrustKind = "to_css"
+)
+
+// TrustLevel is the decision of a TrustPolicy.
+type TrustLevel int
+
+const (
+	// TrustAllow marks the content as trusted, it is rendered as is.
+	TrustAllow TrustLevel = iota
+	// TrustEscape renders the content as a plain string, escaped by
+	// html/template according to its context.
+	TrustEscape
+	// TrustDeny fails the render.
+	TrustDeny
+)
+
+// TrustPolicy controls the to_html, to_html_attr, to_js and to_css functions,
+// which otherwise let any template author bypass html/template escaping.
+type TrustPolicy interface {
+	// Trust decides how content of the given kind is rendered. It returns
+	// the content to use, which allows sanitizing it before it is trusted.
+	Trust(kind TrustKind, content string) (TrustLevel, string)
+}
+
+// TrustPolicyFunc is an adapter to use ordinary functions as TrustPolicy.
+type TrustPolicyFunc func(kind TrustKind, content string) (TrustLevel, string)
+
+func (f TrustPolicyFunc) Trust(kind TrustKind, content string) (TrustLevel, string) {
+	return f(kind, content)
+}
+
+// TrustPolicyMap is a TrustPolicy with a fixed level per kind. Kinds missing
+// from the map are denied.
+type TrustPolicyMap map[TrustKind]TrustLevel
+
+func (m TrustPolicyMap) Trust(kind TrustKind, content string) (TrustLevel, string) {
+	if level, ok := m[kind]; ok {
+		return level, content
+	}
+	return TrustDeny, content
+}
+
+var (
+	// AllowTrusted trusts all content, which is the behavior of Funcs.
+	AllowTrusted TrustPolicy = TrustPolicyMap{TrustHTML: TrustAllow, TrustHTMLAttr: TrustAllow, TrustJS: TrustAllow, TrustCSS: TrustAllow}
+	// EscapeTrusted escapes all content as if it wasn't marked as trusted.
+	EscapeTrusted TrustPolicy = TrustPolicyMap{TrustHTML: TrustEscape, TrustHTMLAttr: TrustEscape, TrustJS: TrustEscape, TrustCSS: TrustEscape}
+	// DenyTrusted fails any render that marks content as trusted.
+	DenyTrusted TrustPolicy = TrustPolicyMap{}
+)
+
+// trustFuncs returns replacements for the functions of funcMap that are
+// controlled by policy. Functions not present in funcMap are not added.
+func trustFuncs(policy TrustPolicy, funcMap template.FuncMap) template.FuncMap {
+	funcs := template.FuncMap{
+		string(TrustHTML): func(s string) (any, error) {
+			return trust(policy, TrustHTML, s, func(s string) any { return template.HTML(s) })
+		},
+		string(TrustHTMLAttr): func(s string) (any, error) {
+			return trust(policy, TrustHTMLAttr, s, func(s string) any { return template.HTMLAttr(s) })
+		},
+		string(TrustJS): func(s string) (any, error) {
+			return trust(policy, TrustJS, s, func(s string) any { return template.JS(s) })
+		},
+		string(TrustCSS): func(s string) (any, error) {
+			return trust(policy, TrustCSS, s, func(s string) any { return template.CSS(s) })
+		},
+	}
+
+	for name := range funcs {
+		if _, ok := funcMap[name]; !ok {
+			delete(funcs, name)
+		}
+	}
+	return funcs
+}
+
+func trust(policy TrustPolicy, kind TrustKind, content string, trusted func(string) any) (any, error) {
+	level, content := policy.Trust(kind, content)
+	switch level {
+	case TrustAllow:
+		return trusted(content), nil
+	case TrustEscape:
+		return content, nil
+	default:
+		return nil, fmt.Errorf("%s: %w", kind, ErrUntrustedContent)
+	}
+}
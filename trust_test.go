@@ -0,0 +1,103 @@
+package got
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustPolicyMap(t *testing.T) {
+	policy := TrustPolicyMap{TrustHTML: TrustAllow, TrustJS: TrustEscape}
+
+	level, content := policy.Trust(TrustHTML, "<b>")
+	assert.Equal(t, TrustAllow, level)
+	assert.Equal(t, "<b>", content)
+
+	level, _ = policy.Trust(TrustJS, "alert(1)")
+	assert.Equal(t, TrustEscape, level)
+
+	level, _ = policy.Trust(TrustCSS, "color: red")
+	assert.Equal(t, TrustDeny, level)
+}
+
+func TestTrustFuncs(t *testing.T) {
+	sanitize := TrustPolicyFunc(func(kind TrustKind, content string) (TrustLevel, string) {
+		if kind == TrustHTML {
+			return TrustAllow, strings.ReplaceAll(content, "<script>", "")
+		}
+		return TrustDeny, content
+	})
+
+	funcs := trustFuncs(sanitize, Funcs)
+	require.Len(t, funcs, 4)
+
+	v, err := funcs["to_html"].(func(string) (any, error))("<script><b>")
+	require.NoError(t, err)
+	assert.Equal(t, template.HTML("<b>"), v)
+
+	_, err = funcs["to_js"].(func(string) (any, error))("alert(1)")
+	assert.ErrorIs(t, err, ErrUntrustedContent)
+	assert.Contains(t, err.Error(), "to_js")
+
+	funcs = trustFuncs(sanitize, template.FuncMap{"to_css": Funcs["to_css"]})
+	assert.Len(t, funcs, 1)
+}
+
+func TestTheme_SetTrustPolicy(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<p>{{to_html .}}</p>`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+	ctx := context.Background()
+
+	assert.Nil(t, theme.TrustPolicy())
+
+	tests := []struct {
+		name     string
+		policy   TrustPolicy
+		expected string
+		err      bool
+	}{
+		{"unrestricted", nil, "<p><b>bold</b></p>", false},
+		{"allow", AllowTrusted, "<p><b>bold</b></p>", false},
+		{"escape", EscapeTrusted, "<p>&lt;b&gt;bold&lt;/b&gt;</p>", false},
+		{"deny", DenyTrusted, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			theme.SetTrustPolicy(tt.policy)
+			assert.Equal(t, tt.policy, theme.TrustPolicy())
+
+			var buf strings.Builder
+			err := theme.Write(ctx, &buf, "page", "<b>bold</b>")
+			if tt.err {
+				assert.ErrorIs(t, err, ErrUntrustedContent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+func TestTheme_SetTrustPolicy_JS(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page", `<script>var x = {{to_js .}};</script>`)
+
+	theme := NewTheme("test", store)
+	theme.AddFuncMap(Funcs)
+	theme.SetTrustPolicy(EscapeTrusted)
+
+	var buf strings.Builder
+	require.NoError(t, theme.Write(context.Background(), &buf, "page", "alert(1)"))
+	assert.Equal(t, `<script>var x = "alert(1)";</script>`, buf.String())
+
+	assert.NoError(t, theme.Write(context.Background(), io.Discard, "page", "1"))
+}
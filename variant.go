@@ -0,0 +1,86 @@
+package got
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+type variantKey struct{}
+
+// WithVariant returns a context rendering pages in the named variant, such
+// as "amp".
+//
+// Every template of a variant render, the page, its layouts and partials,
+// is resolved to its variant when the theme has one, named with the variant
+// before the extension ("posts/show.amp.html" for "posts/show.html"), and
+// shared with the default render otherwise. A variant page can so declare a
+// different layout chain while reusing the partials it doesn't override.
+func WithVariant(ctx context.Context, variant string) context.Context {
+	return context.WithValue(ctx, variantKey{}, variant)
+}
+
+// VariantFrom returns the variant set by WithVariant, empty if none.
+func VariantFrom(ctx context.Context) string {
+	variant, _ := ctx.Value(variantKey{}).(string)
+	return variant
+}
+
+// variantName returns the name of the variant of a template.
+func variantName(name, variant string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + variant + ext
+}
+
+// variantTemplate is the variant of a template standing in for the
+// template under its name.
+type variantTemplate struct {
+	Template
+	name string
+}
+
+func (v variantTemplate) Name() string {
+	return v.name
+}
+
+func (v variantTemplate) Path() string {
+	if p := v.Template.Path(); p != v.Template.Name() {
+		return p
+	}
+	return v.name
+}
+
+func (v variantTemplate) Stat() TemplateInfo {
+	return Stat(v.Template)
+}
+
+// findVariant returns the variant of the named template when ctx renders a
+// variant the theme has, the template itself otherwise. The source is the
+// template as stored, the item stands in for it under the requested name.
+func (t *Theme) findVariant(ctx context.Context, fetched map[string]Template, name string) (item, source Template, err error) {
+	if variant := VariantFrom(ctx); variant != "" {
+		source, err = t.findFetched(ctx, fetched, variantName(name, variant))
+		if err == nil {
+			return variantTemplate{Template: source, name: name}, source, nil
+		}
+		if !errors.Is(err, ErrTemplateNotFound) {
+			return nil, nil, err
+		}
+	}
+
+	source, err = t.findFetched(ctx, fetched, name)
+	return source, source, err
+}
+
+// cacheName returns the name the page is cached under for the variant and
+// locale rendered by ctx.
+func cacheName(ctx context.Context, name string) string {
+	if variant := VariantFrom(ctx); variant != "" {
+		name += "\x00" + variant
+	}
+	if locale, ok := LocaleFrom(ctx); ok {
+		name += "\x00locale:" + locale.String()
+	}
+	return name
+}
@@ -0,0 +1,104 @@
+package got
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "posts/show.html", want: "posts/show.amp.html"},
+		{name: "layouts/base", want: "layouts/base.amp"},
+		{name: "a.b/c.gohtml", want: "a.b/c.amp.gohtml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, variantName(tt.name, "amp"))
+		})
+	}
+}
+
+func TestTheme_Write_Variant(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "layouts/base.html", `<html>{{template "content" .}}</html>`)
+	store.Add("test", "layouts/base.amp.html", `<html amp>{{template "content" .}}</html>`)
+	store.Add("test", "partials/card.html", `<div>{{.}}</div>`)
+	store.Add("test", "posts/show.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{template "partials/card.html" .}}<script src="/app.js"></script>{{end}}`)
+	store.Add("test", "posts/show.amp.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}{{template "partials/card.html" .}}{{end}}`)
+	store.Add("test", "about.html", "<!-- layouts/base.html -->\n"+`{{define "content"}}about{{end}}`)
+
+	theme := NewTheme("test", store)
+	amp := WithVariant(context.Background(), "amp")
+	assert.Equal(t, "amp", VariantFrom(amp))
+	assert.Empty(t, VariantFrom(context.Background()))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		page string
+		want string
+	}{
+		{
+			name: "default",
+			ctx:  context.Background(),
+			page: "posts/show.html",
+			want: `<html><div>a</div><script src="/app.js"></script></html>`,
+		},
+		{
+			name: "variant",
+			ctx:  amp,
+			page: "posts/show.html",
+			want: `<html amp><div>a</div></html>`,
+		},
+		{
+			name: "variant layout only",
+			ctx:  amp,
+			page: "about.html",
+			want: `<html amp>about</html>`,
+		},
+		{
+			name: "missing variant",
+			ctx:  WithVariant(context.Background(), "print"),
+			page: "posts/show.html",
+			want: `<html><div>a</div><script src="/app.js"></script></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+			require.NoError(t, theme.Write(tt.ctx, &buf, tt.page, "a"))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	_, ok := theme.cache.Load("posts/show.html")
+	assert.True(t, ok)
+	_, ok = theme.cache.Load(cacheName(amp, "posts/show.html"))
+	assert.True(t, ok)
+}
+
+func TestTheme_RenderPage_Variant(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "page.html", `default`)
+	store.Add("test", "page.amp.html", `amp`)
+
+	theme := NewTheme("test", store)
+	theme.SetPageCache(PageCacheOptions{Backend: NewPageCacheMemory(0)})
+
+	page, _, err := theme.RenderPage(context.Background(), "page.html", "/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "default", string(page.Body))
+
+	page, _, err = theme.RenderPage(WithVariant(context.Background(), "amp"), "page.html", "/", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "amp", string(page.Body))
+}
@@ -0,0 +1,199 @@
+package got
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Warm compiles the named pages concurrently into the template cache, so the
+// first renders after a deploy don't pay for parsing. It returns the joined
+// errors of the pages that failed to compile.
+func (t *Theme) Warm(ctx context.Context, names []string) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for _, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+
+		wg.Go(func() {
+			defer func() { <-sem }()
+
+			if _, err := t.compile(ctx, name); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// WarmManifest warms the pages listed by a manifest, see ReadManifest, up
+// to limit pages when limit is positive.
+func (t *Theme) WarmManifest(ctx context.Context, r io.Reader, limit int) error {
+	names, err := ReadManifest(r)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	return t.Warm(ctx, names)
+}
+
+// ReadManifest reads the page names of a warm-up manifest, hottest first.
+//
+// Each line holds a page name, optionally followed by a tab or space and its
+// access count as written by AccessLog.WriteTo. Pages are ordered by
+// decreasing count, then in the order of the manifest. Blank lines and lines
+// starting with # are ignored.
+func ReadManifest(r io.Reader) ([]string, error) {
+	type entry struct {
+		name  string
+		count uint64
+	}
+
+	var entries []entry
+	seen := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e := entry{name: line}
+		if i := strings.LastIndexAny(line, " \t"); i > 0 {
+			if count, err := strconv.ParseUint(line[i+1:], 10, 64); err == nil {
+				e = entry{name: strings.TrimSpace(line[:i]), count: count}
+			}
+		}
+
+		if _, ok := seen[e.name]; ok {
+			continue
+		}
+		seen[e.name] = struct{}{}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("theme: failed to read manifest: %w", err)
+	}
+
+	slices.SortStableFunc(entries, func(a, b entry) int {
+		return cmp.Compare(b.count, a.count)
+	})
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names, nil
+}
+
+// AccessLog counts the renders of each page, to be written as a manifest
+// on shutdown and warmed up on the next start:
+//
+//	log := got.NewAccessLog()
+//	theme.SetAccessLog(log)
+//	...
+//	log.WriteTo(file)
+type AccessLog struct {
+	counts sync.Map
+}
+
+func NewAccessLog() *AccessLog {
+	return &AccessLog{}
+}
+
+// Record counts a render of the named page.
+func (l *AccessLog) Record(name string) {
+	counter, ok := l.counts.Load(name)
+	if !ok {
+		counter, _ = l.counts.LoadOrStore(name, new(atomic.Uint64))
+	}
+	counter.(*atomic.Uint64).Add(1)
+}
+
+// Count returns the number of recorded renders of the named page.
+func (l *AccessLog) Count(name string) uint64 {
+	if counter, ok := l.counts.Load(name); ok {
+		return counter.(*atomic.Uint64).Load()
+	}
+	return 0
+}
+
+// Hottest returns the names of the n most rendered pages, all of them when
+// n isn't positive.
+func (l *AccessLog) Hottest(n int) []string {
+	type entry struct {
+		name  string
+		count uint64
+	}
+
+	var entries []entry
+	l.counts.Range(func(key, value any) bool {
+		entries = append(entries, entry{key.(string), value.(*atomic.Uint64).Load()})
+		return true
+	})
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), strings.Compare(a.name, b.name))
+	})
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// WriteTo writes the recorded pages as a manifest, hottest first.
+func (l *AccessLog) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, name := range l.Hottest(0) {
+		b.WriteString(name)
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatUint(l.Count(name), 10))
+		b.WriteByte('\n')
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// AccessLog returns the access log recording the renders of the theme, nil
+// if none is set.
+func (t *Theme) AccessLog() *AccessLog {
+	return t.access.Load()
+}
+
+// SetAccessLog records the pages rendered by the theme into log. A nil log
+// stops recording.
+func (t *Theme) SetAccessLog(log *AccessLog) {
+	t.access.Store(log)
+}
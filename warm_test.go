@@ -0,0 +1,103 @@
+package got
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTheme_Warm(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "a", `a`)
+	store.Add("test", "b", `b`)
+	store.Add("test", "broken", `{{if}}`)
+
+	theme := NewTheme("test", store)
+
+	err := theme.Warm(context.Background(), []string{"a", "b", "broken", "missing"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	var parseErr *ParseError
+	assert.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, 2, theme.cache.Len())
+
+	require.NoError(t, theme.Warm(context.Background(), []string{"a", "b"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, NewTheme("test", store).Warm(ctx, make([]string, 64)), context.Canceled)
+}
+
+func TestReadManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []string
+	}{
+		{
+			name:     "names",
+			manifest: "b\na\n\n# comment\nc\n",
+			want:     []string{"b", "a", "c"},
+		},
+		{
+			name:     "counts",
+			manifest: "a\t1\nb 10\nc\nd\t5\n",
+			want:     []string{"b", "d", "a", "c"},
+		},
+		{
+			name:     "duplicates",
+			manifest: "a\t1\na\t10\n",
+			want:     []string{"a"},
+		},
+		{
+			name:     "name with spaces",
+			manifest: "my page.html\t3\nother page.html\n",
+			want:     []string{"my page.html", "other page.html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := ReadManifest(strings.NewReader(tt.manifest))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	store := NewStoreMemory()
+	store.Add("test", "a", `a`)
+	store.Add("test", "b", `b`)
+
+	log := NewAccessLog()
+	theme := NewTheme("test", store)
+	theme.SetAccessLog(log)
+	assert.Same(t, log, theme.AccessLog())
+
+	for _, name := range []string{"a", "b", "b", "b", "a", "missing"} {
+		_ = theme.Write(context.Background(), io.Discard, name, nil)
+	}
+
+	assert.Equal(t, uint64(2), log.Count("a"))
+	assert.Equal(t, uint64(3), log.Count("b"))
+	assert.Equal(t, uint64(0), log.Count("missing"))
+	assert.Equal(t, []string{"b", "a"}, log.Hottest(0))
+	assert.Equal(t, []string{"b"}, log.Hottest(1))
+
+	var manifest strings.Builder
+	n, err := log.WriteTo(&manifest)
+	require.NoError(t, err)
+	assert.Equal(t, int64(manifest.Len()), n)
+	assert.Equal(t, "b\t3\na\t2\n", manifest.String())
+
+	warmed := NewTheme("test", store)
+	require.NoError(t, warmed.WarmManifest(context.Background(), strings.NewReader(manifest.String()), 1))
+	assert.Equal(t, 1, warmed.cache.Len())
+	_, ok := warmed.cache.Load("b")
+	assert.True(t, ok)
+}